@@ -0,0 +1,79 @@
+package accessibleby
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clause/permissions"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	typeKey = "accessible_by"
+)
+
+var (
+	documentation = clause.ClauseDocumentation{
+		Summary: "Searches for objects a user can access with at least the given permission, whether they own them or they were shared with them",
+		Args: map[string]clause.ClauseArgumentDocumentation{
+			"user":       {Type: "string", Summary: "The user who must be able to access objects. Unqualified usernames are handled as in the 'permissions' clause. If blank, the current user is used."},
+			"permission": {Type: "string", Summary: "The minimum permission the user must have; one of 'read', 'write', or 'own'. Defaults to 'read'."},
+		},
+	}
+)
+
+type AccessibleByArgs struct {
+	User       string
+	Permission string
+}
+
+func AccessibleByProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	var realArgs AccessibleByArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := clauseutils.UserOrCurrent(ctx, realArgs.User)
+	if err != nil {
+		return nil, err
+	}
+
+	permission := realArgs.Permission
+	if permission == "" {
+		permission = "read"
+	}
+
+	return permissions.PermissionsQuery(ctx, permissions.PermissionsArgs{Users: []string{user}, Permission: permission, PermissionRecurse: true})
+}
+
+func AccessibleBySummary(ctx context.Context, args map[string]interface{}) (string, error) {
+	var realArgs AccessibleByArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := clauseutils.UserOrCurrent(ctx, realArgs.User)
+	if err != nil {
+		user = "<current user>"
+	}
+
+	if realArgs.Permission == "" || realArgs.Permission == "read" {
+		return fmt.Sprintf("accessible_by=\"%s\"", user), nil
+	}
+	return fmt.Sprintf("accessible_by=\"%s\"(%s)", user, realArgs.Permission), nil
+}
+
+func AccessibleByCost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	return clause.ClauseCost{Cost: clauseutils.NestedCost + clauseutils.WildcardCost, Terms: 1}, nil
+}
+
+func Register(qd *querydsl.QueryDSL) {
+	qd.AddClauseTypeSummarized(typeKey, AccessibleByProcessor, documentation, AccessibleBySummary)
+	qd.SetClauseCoster(typeKey, AccessibleByCost)
+}
@@ -0,0 +1,66 @@
+package accessibleby
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+func TestAccessibleByProcessor(t *testing.T) {
+	cases := []struct {
+		user         interface{}
+		permission   string
+		currentUser  string
+		expectedUser string
+		expectedPerm elastic.Query
+		shouldErr    bool
+	}{
+		{user: "mian#iplant", expectedUser: "mian#iplant"},
+		{user: "mian#iplant", permission: "write", expectedUser: "mian#iplant", expectedPerm: elastic.NewTermsQuery("userPermissions.permission", "write", "own")},
+		{user: "mian#iplant", permission: "own", expectedUser: "mian#iplant", expectedPerm: elastic.NewTermQuery("userPermissions.permission", "own")},
+		{currentUser: "ipctest#iplant", expectedUser: "ipctest#iplant"},
+		{user: "mian#iplant", permission: "wrong", shouldErr: true}, // bad permission
+		{shouldErr: true},            // no user and no current user
+		{user: 444, shouldErr: true}, // bad type
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%T(%+v)-%s-current:%s", c.user, c.user, c.permission, c.currentUser), func(t *testing.T) {
+			ctx := context.Background()
+			if c.currentUser != "" {
+				ctx = clauseutils.WithCurrentUser(ctx, c.currentUser)
+			}
+			args := map[string]interface{}{"user": c.user, "permission": c.permission}
+
+			query, err := AccessibleByProcessor(ctx, args)
+			if c.shouldErr && err == nil {
+				t.Errorf("AccessibleByProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("AccessibleByProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				inner := elastic.NewBoolQuery()
+				if c.expectedPerm != nil {
+					inner.Must(c.expectedPerm)
+				}
+				inner.Must(elastic.NewTermsQuery("userPermissions.user", c.expectedUser))
+				expected := elastic.NewNestedQuery("userPermissions", inner)
+
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
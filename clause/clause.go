@@ -6,6 +6,19 @@ import (
 	"github.com/olivere/elastic/v7"
 )
 
+// DocumentKind identifies a kind of indexed object, such as a file or a folder
+type DocumentKind string
+
+const (
+	// File is the document kind for data objects
+	File DocumentKind = "file"
+	// Folder is the document kind for collections
+	Folder DocumentKind = "folder"
+
+	// DocumentKindField is the indexed field holding an object's DocumentKind
+	DocumentKindField = "doc_type"
+)
+
 // ClauseType is an alias for string used as the key for locating processors and documentation
 type ClauseType string
 
@@ -15,6 +28,21 @@ type ClauseProcessor func(ctx context.Context, args map[string]interface{}) (ela
 // ClauseSummarizer is a function taking a context and arguments for a given clause type and producing a summary string
 type ClauseSummarizer func(ctx context.Context, args map[string]interface{}) (string, error)
 
+// ClauseCost estimates how expensive a single clause is to run
+type ClauseCost struct {
+	// Cost is a relative estimate of the clause's expense, where a single term query costs 1
+	Cost float64 `json:"cost"`
+	// Terms is the number of terms (users, tags, values, etc.) the clause matches against
+	Terms int `json:"terms"`
+	// LeadingWildcards is the number of patterns in the clause starting with a wildcard
+	LeadingWildcards int `json:"leading_wildcards"`
+	// TagLookups is the number of terms lookups the clause makes against the tag index
+	TagLookups int `json:"tag_lookups"`
+}
+
+// ClauseCoster is a function taking a context and arguments for a given clause type and estimating its cost
+type ClauseCoster func(ctx context.Context, args map[string]interface{}) (ClauseCost, error)
+
 // ClauseArgumentDocumentation describes a single argument for a clause. The 'type' should look like a golang type, though this is not checked.
 type ClauseArgumentDocumentation struct {
 	Type    string `json:"type"`
@@ -22,7 +50,34 @@ type ClauseArgumentDocumentation struct {
 }
 
 // ClauseDocumentation describes a clause with an overall summary plus documentation of each argument.
+// Kinds lists the document kinds the clause can match; an empty list means it applies to all of them.
 type ClauseDocumentation struct {
 	Summary string                                 `json:"summary"`
 	Args    map[string]ClauseArgumentDocumentation `json:"args"`
+	Kinds   []DocumentKind                         `json:"kinds,omitempty"`
+}
+
+// AppliesTo checks whether a clause with this documentation can match documents of the given kind
+func (d ClauseDocumentation) AppliesTo(kind DocumentKind) bool {
+	if len(d.Kinds) == 0 {
+		return true
+	}
+	for _, k := range d.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ClausePolicy declaratively restricts who may use a clause type.
+type ClausePolicy struct {
+	// AllowedRoles, if not empty, lists the roles permitted to use the clause at all
+	AllowedRoles []string
+	// SelfOnlyArgs names arguments (strings or lists of strings) which may only reference the caller's own username. Values are
+	// resolved to exact qualified usernames with the configured UserResolver, and must resolve only to the caller's. Names are
+	// matched without regard to case, as arguments are decoded.
+	SelfOnlyArgs []string
+	// ExemptRoles lists roles which may reference any username in SelfOnlyArgs
+	ExemptRoles []string
 }
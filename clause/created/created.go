@@ -2,13 +2,10 @@ package created
 
 import (
 	"context"
-	"errors"
-	"fmt"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
-	"github.com/cyverse-de/querydsl/v2/clauseutils"
-	"github.com/mitchellh/mapstructure"
+	"github.com/cyverse-de/querydsl/v2/clause/daterange"
 	"github.com/olivere/elastic/v7"
 )
 
@@ -17,71 +14,23 @@ const (
 )
 
 var (
-	documentation = clause.ClauseDocumentation{
-		Summary: "Searches based on an object's creation date",
-		Args: map[string]clause.ClauseArgumentDocumentation{
-			"from": {Type: "string", Summary: "The start date for the range (inclusive). Pass as a string, milliseconds since epoch or in YYYY-MM-DDTHH:MM:SS.mss<TZ> format, where TZ can either be 'Z' or an offset in ±hh:mm format, or YYYY-MM-DD which assumes UTC and 0 values for all other fields."},
-			"to":   {Type: "string", Summary: "The end date for the range (inclusive). Pass as a string, milliseconds since epoch or in YYYY-MM-DDTHH:MM:SS.mss<TZ> format, where TZ can either be 'Z' or an offset in ±hh:mm format, or YYYY-MM-DD which assumes UTC and 0 values for all other fields."},
-		},
-	}
+	dateRange = daterange.New(typeKey, "dateCreated", "creation date")
 )
 
-type CreatedArgs struct {
-	From string
-	To   string
-}
-
-func CreatedProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
-	var realArgs CreatedArgs
-	err := mapstructure.Decode(args, &realArgs)
-	if err != nil {
-		return nil, err
-	}
-
-	if realArgs.From == "" && realArgs.To == "" {
-		return nil, errors.New("Neither from nor to was passed, cannot create clause.")
-	}
-
-	var from, to int64
-	var rangetype clauseutils.RangeType
+type CreatedArgs = daterange.DateRangeArgs
 
-	if realArgs.From != "" {
-		rangetype = clauseutils.LowerOnly
-		from, err = clauseutils.DateToEpochMs(realArgs.From)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if realArgs.To != "" {
-		if rangetype == clauseutils.LowerOnly {
-			rangetype = clauseutils.Both
-		} else {
-			rangetype = clauseutils.UpperOnly
-		}
-		to, err = clauseutils.DateToEpochMs(realArgs.To)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return clauseutils.CreateRangeQuery("dateCreated", rangetype, from, to), nil
+func CreatedProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	return dateRange.Processor(ctx, args)
 }
 
-func CreatedSummary(_ context.Context, args map[string]interface{}) (string, error) {
-	var realArgs CreatedArgs
-	err := mapstructure.Decode(args, &realArgs)
-	if err != nil {
-		return "", err
-	}
-
-	if realArgs.From == "" && realArgs.To == "" {
-		return "", errors.New("Neither from nor to was passed, cannot create clause.")
-	}
+func CreatedSummary(ctx context.Context, args map[string]interface{}) (string, error) {
+	return dateRange.Summary(ctx, args)
+}
 
-	return fmt.Sprintf("created=%s--%s", realArgs.From, realArgs.To), nil
+func CreatedCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	return dateRange.Cost(ctx, args)
 }
 
 func Register(qd *querydsl.QueryDSL) {
-	qd.AddClauseTypeSummarized(typeKey, CreatedProcessor, documentation, CreatedSummary)
+	dateRange.Register(qd)
 }
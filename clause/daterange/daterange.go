@@ -0,0 +1,152 @@
+// Package daterange builds clauses searching a date field by range, so each date field a deployment indexes can be
+// searched with a single registration:
+//
+//	daterange.New("accessed", "dateAccessed", "last access date").Register(qd)
+package daterange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
+)
+
+// DateRange is a clause type searching a single date field
+type DateRange struct {
+	typeKey     clause.ClauseType
+	field       string
+	description string
+}
+
+type DateRangeArgs struct {
+	From          string
+	To            string
+	FromExclusive bool `mapstructure:"from_exclusive"`
+	ToExclusive   bool `mapstructure:"to_exclusive"`
+	Timezone      string
+}
+
+// New creates a DateRange clause type, where field is the date field to search and description describes it for the
+// clause's documentation, such as "creation date"
+func New(typeKey clause.ClauseType, field, description string) *DateRange {
+	return &DateRange{typeKey: typeKey, field: field, description: description}
+}
+
+// Documentation returns the clause's documentation
+func (d *DateRange) Documentation() clause.ClauseDocumentation {
+	return clause.ClauseDocumentation{
+		Summary: fmt.Sprintf("Searches based on an object's %s", d.description),
+		Args: map[string]clause.ClauseArgumentDocumentation{
+			string(d.typeKey): {Type: "string", Summary: clauseutils.RangeExpressionDocumentation + " Dates are in any format 'from' accepts, such as '2017-01-01..2017-12-31' or '< now-30d'."},
+			"from":            {Type: "string", Summary: "The start date for the range (inclusive, unless from_exclusive is set). If unset, the range has no start. Pass as a string, milliseconds since epoch, seconds since epoch prefixed with @, in RFC3339 format with or without fractional seconds, or as YYYY-MM-DDTHH:MM:SS, YYYY-MM-DDTHH:MM, YYYY-MM-DDTHH, YYYY-MM-DD, YYYY-MM, or YYYY, which are in the time zone given by 'timezone'. Relative dates are also accepted: Elasticsearch date math such as 'now-7d/d' or '2017-09-23||+1M', shorthand such as '-1w', 'today', 'yesterday', or 'start of month'. Rounded and partial dates resolve to their first millisecond, or their last if from_exclusive is set."},
+			"to":              {Type: "string", Summary: "The end date for the range (inclusive, unless to_exclusive is set). If unset, the range has no end. Accepts the same formats as 'from', but rounded and partial dates resolve to their last millisecond, so 'today' or '2017-09-23' include that entire day, or their first if to_exclusive is set."},
+			"from_exclusive":  {Type: "bool", Summary: "Whether to leave the start date itself out of the range, so a from of '2017-09-23' matches only dates after that day"},
+			"to_exclusive":    {Type: "bool", Summary: "Whether to leave the end date itself out of the range, so a to of '2017-09-23' matches only dates before that day"},
+			"timezone":        {Type: "string", Summary: "The time zone for dates without one, and for rounding and keywords like 'today', either an IANA name such as America/Phoenix or an offset such as -07:00. Defaults to UTC."},
+		},
+	}
+}
+
+// decodeArgs decodes the clause's args, filling in from, to, and their exclusivity from a range in shorthand passed as
+// the argument named after the clause type, which is also returned
+func (d *DateRange) decodeArgs(args map[string]interface{}) (DateRangeArgs, *clauseutils.RangeExpression, error) {
+	var realArgs DateRangeArgs
+	expr, args, err := clauseutils.RangeExpressionArg(args, string(d.typeKey))
+	if err != nil {
+		return realArgs, nil, err
+	}
+
+	err = mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return realArgs, nil, err
+	}
+
+	if expr != nil {
+		if realArgs.From != "" || realArgs.To != "" || realArgs.FromExclusive || realArgs.ToExclusive {
+			return realArgs, nil, fmt.Errorf("The %s argument cannot be combined with from, to, from_exclusive, or to_exclusive.", d.typeKey)
+		}
+		realArgs.From, realArgs.To = expr.From, expr.To
+		realArgs.FromExclusive, realArgs.ToExclusive = expr.FromExclusive, expr.ToExclusive
+	}
+	return realArgs, expr, nil
+}
+
+func (d *DateRange) Processor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	realArgs, _, err := d.decodeArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if realArgs.From == "" && realArgs.To == "" {
+		return nil, errors.New("Neither from nor to was passed, cannot create clause.")
+	}
+
+	loc, err := clauseutils.LoadTimezone(realArgs.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := clauseutils.NewRangeBuilder(d.field)
+
+	// as in Elasticsearch, rounding moves exclusive bounds away from the rounded date
+	if realArgs.From != "" {
+		rounding := clauseutils.RoundDown
+		if realArgs.FromExclusive {
+			rounding = clauseutils.RoundUp
+		}
+		from, err := clauseutils.ParseDateIn(ctx, realArgs.From, rounding, loc)
+		if err != nil {
+			return nil, err
+		}
+		rb.From(from, realArgs.FromExclusive)
+	}
+
+	if realArgs.To != "" {
+		rounding := clauseutils.RoundUp
+		if realArgs.ToExclusive {
+			rounding = clauseutils.RoundDown
+		}
+		to, err := clauseutils.ParseDateIn(ctx, realArgs.To, rounding, loc)
+		if err != nil {
+			return nil, err
+		}
+		rb.To(to, realArgs.ToExclusive)
+	}
+
+	return rb.Build()
+}
+
+func (d *DateRange) Summary(_ context.Context, args map[string]interface{}) (string, error) {
+	realArgs, expr, err := d.decodeArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	if realArgs.From == "" && realArgs.To == "" {
+		return "", errors.New("Neither from nor to was passed, cannot create clause.")
+	}
+
+	summary := clauseutils.RangeSummary(string(d.typeKey), realArgs.From, realArgs.To, realArgs.FromExclusive, realArgs.ToExclusive)
+	if expr != nil {
+		summary = expr.Summary(string(d.typeKey))
+	}
+	if realArgs.Timezone != "" {
+		return fmt.Sprintf("%s(%s)", summary, realArgs.Timezone), nil
+	}
+	return summary, nil
+}
+
+func (d *DateRange) Cost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	return clause.ClauseCost{Cost: clauseutils.RangeCost, Terms: 1}, nil
+}
+
+// Register adds the clause type to qd
+func (d *DateRange) Register(qd *querydsl.QueryDSL) {
+	qd.AddClauseTypeSummarized(d.typeKey, d.Processor, d.Documentation(), d.Summary)
+	qd.SetClauseCoster(d.typeKey, d.Cost)
+}
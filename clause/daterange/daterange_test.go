@@ -0,0 +1,130 @@
+package daterange
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+func TestDateRangeProcessor(t *testing.T) {
+	now := time.Date(2017, 9, 20, 15, 30, 0, 0, time.UTC)
+	ctx := clauseutils.WithClock(context.Background(), func() time.Time { return now })
+	ms := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+	d := New("accessed", "dateAccessed", "last access date")
+
+	cases := []struct {
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			args:     map[string]interface{}{"from": "2017-09-01", "to": "2017-09-23"},
+			expected: elastic.NewRangeQuery("dateAccessed").Gte(ms(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC))).Lte(ms(time.Date(2017, 9, 24, 0, 0, 0, 0, time.UTC)) - 1),
+		},
+		{
+			args:     map[string]interface{}{"from": "now-7d"},
+			expected: elastic.NewRangeQuery("dateAccessed").Gte(ms(now.AddDate(0, 0, -7))),
+		},
+		{
+			args:     map[string]interface{}{"to": "2017-09"},
+			expected: elastic.NewRangeQuery("dateAccessed").Lte(ms(time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)) - 1),
+		},
+		{
+			args:     map[string]interface{}{"from": "2017-09-01", "from_exclusive": true, "to": "2017-09-23", "to_exclusive": true},
+			expected: elastic.NewRangeQuery("dateAccessed").Gt(ms(time.Date(2017, 9, 2, 0, 0, 0, 0, time.UTC)) - 1).Lt(ms(time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC))),
+		},
+		{
+			args:     map[string]interface{}{"from": "2017-09-01", "timezone": "-07:00"},
+			expected: elastic.NewRangeQuery("dateAccessed").Gte(ms(time.Date(2017, 9, 1, 7, 0, 0, 0, time.UTC))),
+		},
+		{
+			args:     map[string]interface{}{"accessed": "2017-01-01..2017-12-31"},
+			expected: elastic.NewRangeQuery("dateAccessed").Gte(ms(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))).Lte(ms(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)) - 1),
+		},
+		{
+			args:     map[string]interface{}{"accessed": "< now-30d"},
+			expected: elastic.NewRangeQuery("dateAccessed").Lt(ms(now.AddDate(0, 0, -30))),
+		},
+		{
+			args:     map[string]interface{}{"accessed": "2017-09-23", "timezone": "-07:00"},
+			expected: elastic.NewRangeQuery("dateAccessed").Gte(ms(time.Date(2017, 9, 23, 7, 0, 0, 0, time.UTC))).Lte(ms(time.Date(2017, 9, 24, 7, 0, 0, 0, time.UTC)) - 1),
+		},
+		{args: map[string]interface{}{"accessed": "< now", "to": "now"}, shouldErr: true},                                 // both shorthand and to
+		{args: map[string]interface{}{"accessed": ">"}, shouldErr: true},                                                  // bad shorthand
+		{args: map[string]interface{}{"from": "2017-09-23", "to": "2017-09-01"}, shouldErr: true},                         // inverted
+		{args: map[string]interface{}{"from": "2017-09-23", "from_exclusive": true, "to": "2017-09-23"}, shouldErr: true}, // empty
+		{args: map[string]interface{}{}, shouldErr: true},                                                                 // no bounds
+		{args: map[string]interface{}{"from": "someday"}, shouldErr: true},                                                // bad date
+		{args: map[string]interface{}{"from": "2017", "timezone": "Nowhere/Special"}, shouldErr: true},                    // bad timezone
+		{args: map[string]interface{}{"from": "2017", "from_exclusive": "yes"}, shouldErr: true},                          // bad type
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.args), func(t *testing.T) {
+			query, err := d.Processor(ctx, c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("Processor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("Processor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestDateRangeSummary(t *testing.T) {
+	d := New("accessed", "dateAccessed", "last access date")
+
+	cases := []struct {
+		args     map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"from": "2017-09-01", "to": "2017-09-23"}, "accessed=2017-09-01--2017-09-23"},
+		{map[string]interface{}{"from": "now-7d"}, "accessed=now-7d--"},
+		{map[string]interface{}{"from": "2017", "from_exclusive": true, "to": "2018"}, "accessed=(2017--2018]"},
+		{map[string]interface{}{"to": "2018", "to_exclusive": true}, "accessed=[--2018)"},
+		{map[string]interface{}{"to": "today", "timezone": "America/Phoenix"}, "accessed=--today(America/Phoenix)"},
+		{map[string]interface{}{"accessed": "< now-30d"}, "accessed<now-30d"},
+		{map[string]interface{}{"accessed": "2017-01-01 .. 2017-12-31"}, "accessed=2017-01-01..2017-12-31"},
+		{map[string]interface{}{"accessed": "today", "timezone": "-07:00"}, "accessed=today(-07:00)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := d.Summary(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("Summary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
+
+func TestDateRangeRegister(t *testing.T) {
+	qd := querydsl.New()
+	New("accessed", "dateAccessed", "last access date").Register(qd)
+
+	docs := qd.GetDocumentation()
+	if docs["accessed"].Summary != "Searches based on an object's last access date" {
+		t.Errorf("Got documentation %+v", docs["accessed"])
+	}
+}
@@ -0,0 +1,76 @@
+package doctype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	typeKey = "type"
+)
+
+var (
+	documentation = clause.ClauseDocumentation{
+		Summary: "Searches based on the kind of object, either a file or a folder",
+		Args: map[string]clause.ClauseArgumentDocumentation{
+			"type": {Type: "string", Summary: "The kind of object to search for; should be one of 'file' or 'folder'."},
+		},
+	}
+)
+
+type DocTypeArgs struct {
+	Type string
+}
+
+func validateArgs(realArgs DocTypeArgs) error {
+	if realArgs.Type == "" {
+		return errors.New("No type was passed, cannot create clause.")
+	}
+
+	kind := clause.DocumentKind(realArgs.Type)
+	if kind != clause.File && kind != clause.Folder {
+		return fmt.Errorf("Got a type of %q, but expected file or folder.", realArgs.Type)
+	}
+	return nil
+}
+
+func DocTypeProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+	var realArgs DocTypeArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateArgs(realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := elastic.NewTermQuery(clause.DocumentKindField, realArgs.Type)
+	return query, nil
+}
+
+func DocTypeSummary(_ context.Context, args map[string]interface{}) (string, error) {
+	var realArgs DocTypeArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	err = validateArgs(realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("type=%s", realArgs.Type), nil
+}
+
+func Register(qd *querydsl.QueryDSL) {
+	qd.AddClauseTypeSummarized(typeKey, DocTypeProcessor, documentation, DocTypeSummary)
+}
@@ -0,0 +1,55 @@
+package doctype
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestDocTypeProcessor(t *testing.T) {
+	cases := []struct {
+		docType       interface{}
+		expectedQuery string
+		shouldErr     bool
+	}{
+		{docType: "file", expectedQuery: "file"},
+		{docType: "folder", expectedQuery: "folder"},
+		{docType: "directory", shouldErr: true}, // unknown kind
+		{shouldErr: true},                       // empty type
+		{docType: 444, shouldErr: true},         // bad type
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%T(%+v)", c.docType, c.docType), func(t *testing.T) {
+			args := make(map[string]interface{})
+
+			args["type"] = c.docType
+
+			query, err := DocTypeProcessor(context.Background(), args)
+			if c.shouldErr && err == nil {
+				t.Errorf("DocTypeProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("DocTypeProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+
+				termQuery, ok := source.(map[string]interface{})["term"]
+				if !ok {
+					t.Error("Source did not contain 'term'")
+				}
+
+				kind, ok := termQuery.(map[string]interface{})["doc_type"]
+				if !ok {
+					t.Error("term query did not contain 'doc_type'")
+				}
+
+				if kind.(string) != c.expectedQuery {
+					t.Errorf("query %q did not match expected value %q", kind, c.expectedQuery)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
@@ -14,41 +15,112 @@ import (
 
 const (
 	typeKey = "label"
+
+	// keywordField is the unanalyzed subfield used for literal and fuzzy searches
+	keywordField = "label.keyword"
+	// analyzedField is the analyzed subfield used by match searches
+	analyzedField = "label.analyzed"
+
+	literalMode  = "literal"
+	wildcardMode = "wildcard"
+	luceneMode   = "lucene"
+	matchMode    = "match"
 )
 
 var (
 	documentation = clause.ClauseDocumentation{
 		Summary: "Searches based on an object's label (typically, its filename)",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"label": {Type: "string", Summary: "The label to search for"},
-			"exact": {Type: "bool", Summary: "Whether to search more precisely, or whether the query should be processed to add wildcards"},
+			"label":     {Type: "string", Summary: "The label to search for"},
+			"exact":     {Type: "bool", Summary: "If no mode is set, whether to search the label's terms without adding wildcards (with any query syntax escaped), or whether the query should be processed to add wildcards according to the configured wildcard strategy"},
+			"mode":      {Type: "string", Summary: "How to interpret the label: 'literal' matches the entire label exactly, 'wildcard' adds implicit wildcards with any other query syntax escaped, 'lucene' passes the label through as Lucene query_string syntax, and 'match' matches all of the label's words against an analyzed version of the label, tolerating typos if 'fuzzy' is set. If blank, 'exact' decides."},
+			"fuzzy":     {Type: "bool|int|string", Summary: "Tolerate typos. Either true or 'AUTO' to choose an edit distance based on the label's length, or a maximum edit distance of 0, 1, or 2. Without a mode, the entire label is matched fuzzily; it may otherwise only be used with the 'match' mode."},
+			"fuzziness": {Type: "bool|int|string", Summary: "An alias for 'fuzzy'"},
+			"regex":     {Type: "string", Summary: "A regular expression the label must match, such as ^SRR\\d+_[12]\\.fastq\\.gz$, instead of a label. Use ^ and $ to match the entire label. Lookarounds, backreferences, lazy quantifiers, and patterns likely to be very slow are rejected."},
 		},
 	}
 )
 
 type LabelArgs struct {
-	Label string
-	Exact bool
+	Label     string
+	Exact     bool
+	Mode      string
+	Fuzzy     interface{}
+	Fuzziness interface{}
+	Regex     string
 }
 
-func LabelProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+// validateArgs checks a set of LabelArgs, returning the requested fuzziness if any
+func validateArgs(realArgs LabelArgs) (string, bool, error) {
+	if realArgs.Regex != "" {
+		if realArgs.Label != "" || realArgs.Mode != "" || realArgs.Exact || realArgs.Fuzzy != nil || realArgs.Fuzziness != nil {
+			return "", false, errors.New("A regex cannot be combined with label, exact, mode, or fuzzy.")
+		}
+		return "", false, nil
+	}
+
+	if realArgs.Label == "" {
+		return "", false, errors.New("No label was passed, cannot create clause.")
+	}
+
+	switch realArgs.Mode {
+	case "", literalMode, wildcardMode, luceneMode, matchMode:
+	default:
+		return "", false, fmt.Errorf("Got a mode of %q, but expected literal, wildcard, lucene, or match.", realArgs.Mode)
+	}
+
+	fuzzyArg := realArgs.Fuzzy
+	if fuzzyArg == nil {
+		fuzzyArg = realArgs.Fuzziness
+	}
+	fuzziness, fuzzy, err := clauseutils.ParseFuzziness(fuzzyArg)
+	if err != nil {
+		return "", false, err
+	}
+	if fuzzy && realArgs.Mode != "" && realArgs.Mode != matchMode {
+		return "", false, fmt.Errorf("Fuzzy matching cannot be used with the %q mode.", realArgs.Mode)
+	}
+	return fuzziness, fuzzy, nil
+}
+
+func LabelProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs LabelArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	if realArgs.Label == "" {
-		return nil, errors.New("No label was passed, cannot create clause.")
+	fuzziness, fuzzy, err := validateArgs(realArgs)
+	if err != nil {
+		return nil, err
 	}
 
-	var processedQuery string
-	if realArgs.Exact {
-		processedQuery = realArgs.Label
-	} else {
-		processedQuery = clauseutils.AddImplicitWildcard(realArgs.Label)
+	if realArgs.Regex != "" {
+		return clauseutils.RegexpQuery(ctx, keywordField, realArgs.Regex)
 	}
-	query := elastic.NewQueryStringQuery(processedQuery).Field("label")
+
+	switch realArgs.Mode {
+	case matchMode:
+		query := elastic.NewMatchQuery(analyzedField, realArgs.Label).Operator("and")
+		if fuzzy {
+			query.Fuzziness(fuzziness)
+		}
+		return query, nil
+	case literalMode:
+		return elastic.NewTermQuery(keywordField, realArgs.Label), nil
+	case wildcardMode:
+		return clauseutils.WildcardQuery(ctx, "label", realArgs.Label), nil
+	case luceneMode:
+		return elastic.NewQueryStringQuery(realArgs.Label).Field("label"), nil
+	}
+
+	if fuzzy {
+		return elastic.NewFuzzyQuery(keywordField, realArgs.Label).Fuzziness(fuzziness), nil
+	}
+	if !realArgs.Exact {
+		return clauseutils.WildcardQuery(ctx, "label", realArgs.Label), nil
+	}
+	query := elastic.NewQueryStringQuery(clauseutils.EscapeQueryString(realArgs.Label)).Field("label")
 	return query, nil
 }
 
@@ -59,8 +131,31 @@ func LabelSummary(_ context.Context, args map[string]interface{}) (string, error
 		return "", err
 	}
 
-	if realArgs.Label == "" {
-		return "", errors.New("No label was passed, cannot create clause.")
+	fuzziness, fuzzy, err := validateArgs(realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if realArgs.Regex != "" {
+		return fmt.Sprintf("label=~/%s/", realArgs.Regex), nil
+	}
+
+	if fuzzy {
+		if fuzziness == clauseutils.AutoFuzziness {
+			return fmt.Sprintf("label≈\"%s\"", realArgs.Label), nil
+		}
+		return fmt.Sprintf("label≈\"%s\"(%s)", realArgs.Label, fuzziness), nil
+	}
+
+	switch realArgs.Mode {
+	case matchMode:
+		return fmt.Sprintf("label:match=\"%s\"", realArgs.Label), nil
+	case literalMode:
+		return fmt.Sprintf("label==\"%s\"", realArgs.Label), nil
+	case wildcardMode:
+		return fmt.Sprintf("label~\"%s\"", realArgs.Label), nil
+	case luceneMode:
+		return fmt.Sprintf("label:lucene=\"%s\"", realArgs.Label), nil
 	}
 
 	if realArgs.Exact {
@@ -69,6 +164,47 @@ func LabelSummary(_ context.Context, args map[string]interface{}) (string, error
 	return fmt.Sprintf("label~\"%s\"", realArgs.Label), nil
 }
 
+func LabelCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs LabelArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	_, fuzzy, err := validateArgs(realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	switch {
+	case realArgs.Regex != "":
+		return clauseutils.RegexpCost(realArgs.Regex), nil
+	case realArgs.Mode == matchMode && fuzzy:
+		terms := len(strings.Fields(realArgs.Label))
+		return clause.ClauseCost{Cost: clauseutils.WildcardCost * float64(terms), Terms: terms}, nil
+	case realArgs.Mode == matchMode:
+		terms := len(strings.Fields(realArgs.Label))
+		return clause.ClauseCost{Cost: clauseutils.TermCost * float64(terms), Terms: terms}, nil
+	case fuzzy:
+		return clause.ClauseCost{Cost: clauseutils.WildcardCost, Terms: 1}, nil
+	}
+
+	switch realArgs.Mode {
+	case literalMode:
+		return clause.ClauseCost{Cost: clauseutils.TermCost, Terms: 1}, nil
+	case wildcardMode:
+		return clauseutils.WildcardSearchCost(ctx, realArgs.Label, false), nil
+	case luceneMode:
+		return clauseutils.QueryStringCost(realArgs.Label, true), nil
+	}
+
+	if realArgs.Exact {
+		return clauseutils.QueryStringCost(clauseutils.EscapeQueryString(realArgs.Label), true), nil
+	}
+	return clauseutils.WildcardSearchCost(ctx, realArgs.Label, false), nil
+}
+
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseTypeSummarized(typeKey, LabelProcessor, documentation, LabelSummary)
+	qd.SetClauseCoster(typeKey, LabelCost)
 }
@@ -3,7 +3,10 @@ package label
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/olivere/elastic/v7"
 )
 
 func TestLabelProcessor(t *testing.T) {
@@ -75,3 +78,120 @@ func TestLabelProcessor(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelProcessorModes(t *testing.T) {
+	cases := []struct {
+		mode      string
+		exact     bool
+		fuzzy     interface{}
+		label     string
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{mode: "literal", label: "sample(1) [final].txt", expected: elastic.NewTermQuery("label.keyword", "sample(1) [final].txt")},
+		{mode: "wildcard", label: "a:b", expected: elastic.NewQueryStringQuery(`*a\:b*`).Field("label")},
+		{mode: "lucene", label: "a:b OR c*", expected: elastic.NewQueryStringQuery("a:b OR c*").Field("label")},
+		{exact: true, label: "sample(1) [final].txt", expected: elastic.NewQueryStringQuery(`sample\(1\) \[final\].txt`).Field("label")},
+		{fuzzy: true, label: "sampel.fastq", expected: elastic.NewFuzzyQuery("label.keyword", "sampel.fastq").Fuzziness("AUTO")},
+		{fuzzy: float64(1), label: "sampel.fastq", expected: elastic.NewFuzzyQuery("label.keyword", "sampel.fastq").Fuzziness("1")},
+		{mode: "match", label: "sampel fastq", expected: elastic.NewMatchQuery("label.analyzed", "sampel fastq").Operator("and")},
+		{mode: "match", fuzzy: "AUTO", label: "sampel fastq", expected: elastic.NewMatchQuery("label.analyzed", "sampel fastq").Operator("and").Fuzziness("AUTO")},
+		{mode: "regex", label: "foo", shouldErr: true},                // unknown mode
+		{mode: "literal", fuzzy: true, label: "foo", shouldErr: true}, // fuzzy with an incompatible mode
+		{fuzzy: 5, label: "foo", shouldErr: true},                     // bad edit distance
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s-exact:%t-fuzzy:%v-%s", c.mode, c.exact, c.fuzzy, c.label), func(t *testing.T) {
+			args := map[string]interface{}{"label": c.label, "mode": c.mode, "exact": c.exact, "fuzzy": c.fuzzy}
+
+			query, err := LabelProcessor(context.Background(), args)
+			if c.shouldErr && err == nil {
+				t.Errorf("LabelProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("LabelProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelRegex(t *testing.T) {
+	cases := []struct {
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{args: map[string]interface{}{"regex": `^SRR\d+_[12]\.fastq\.gz$`}, expected: elastic.NewRegexpQuery("label.keyword", `SRR[0-9]+_[12]\.fastq\.gz`).MaxDeterminizedStates(10000)},
+		{args: map[string]interface{}{"regex": "^sample@.*"}, expected: elastic.NewRegexpQuery("label.keyword", `sample\@.*`).MaxDeterminizedStates(10000)},
+		{args: map[string]interface{}{"regex": `\.fastq$`}, expected: elastic.NewRegexpQuery("label.keyword", `.*\.fastq`).MaxDeterminizedStates(10000)},
+		{args: map[string]interface{}{"regex": "(a+)+"}, shouldErr: true},                // catastrophic
+		{args: map[string]interface{}{"regex": "foo(?=bar)"}, shouldErr: true},           // lookaround
+		{args: map[string]interface{}{"regex": "foo[", "label": "foo"}, shouldErr: true}, // combined with label
+		{args: map[string]interface{}{"regex": "foo", "fuzzy": true}, shouldErr: true},   // combined with fuzzy
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.args), func(t *testing.T) {
+			query, err := LabelProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("LabelProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("LabelProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelSummary(t *testing.T) {
+	cases := []struct {
+		args     map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"label": "foo"}, "label~\"foo\""},
+		{map[string]interface{}{"label": "foo", "exact": true}, "label=\"foo\""},
+		{map[string]interface{}{"label": "foo", "mode": "literal"}, "label==\"foo\""},
+		{map[string]interface{}{"label": "foo", "mode": "wildcard"}, "label~\"foo\""},
+		{map[string]interface{}{"label": "foo", "mode": "lucene"}, "label:lucene=\"foo\""},
+		{map[string]interface{}{"label": "foo", "mode": "match"}, "label:match=\"foo\""},
+		{map[string]interface{}{"label": "sampel", "fuzzy": true}, "label≈\"sampel\""},
+		{map[string]interface{}{"label": "sampel", "mode": "match", "fuzziness": "AUTO"}, "label≈\"sampel\""},
+		{map[string]interface{}{"label": "sampel", "fuzzy": 1}, "label≈\"sampel\"(1)"},
+		{map[string]interface{}{"regex": `^SRR\d+$`}, `label=~/^SRR\d+$/`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := LabelSummary(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("LabelSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/cyverse-de/querydsl/v2"
@@ -24,10 +25,15 @@ var (
 			"attribute":       {Type: "string", Summary: "The AVU's attribute field"},
 			"value":           {Type: "string", Summary: "The AVU's value field"},
 			"unit":            {Type: "string", Summary: "The AVU's unit field"},
-			"metadata_types":  {Type: "[]string", Summary: "What types of metadata to search. Can include 'irods', 'cyverse', or blank for both types."},
-			"attribute_exact": {Type: "bool", Summary: "Whether to search the attribute exactly, or add implicit wildcards"},
-			"value_exact":     {Type: "bool", Summary: "Whether to search the value exactly, or add implicit wildcards"},
-			"unit_exact":      {Type: "bool", Summary: "Whether to search the unit exactly, or add implicit wildcards"},
+			"metadata_types":  {Type: "[]string", Summary: "What types of metadata to search. Can include any of the configured metadata namespaces, by default 'irods' and 'cyverse', or blank for all of them."},
+			"attribute_exact": {Type: "bool", Summary: "Whether to search the attribute exactly, or add implicit wildcards according to the configured wildcard strategy"},
+			"value_exact":     {Type: "bool", Summary: "Whether to search the value exactly, or add implicit wildcards according to the configured wildcard strategy"},
+			"unit_exact":      {Type: "bool", Summary: "Whether to search the unit exactly, or add implicit wildcards according to the configured wildcard strategy"},
+			"value_range":     {Type: "object|string", Summary: "A range the AVU's value must fall in, instead of a value: either an object with 'from' and/or 'to', plus optional 'from_exclusive' and 'to_exclusive', or a range in shorthand. " + clauseutils.RangeExpressionDocumentation},
+			"conditions":      {Type: "[]object", Summary: "Several AVU conditions to match, instead of attribute, value, or unit. Each takes the attribute, value, unit, their _exact flags, value_range, and value_type arguments, plus 'same_avu', which is true by default to require that a single AVU match the entire condition, or false to allow its attribute, value, and unit to each match different AVUs on the object."},
+			"min_matches":     {Type: "int", Summary: "How many of the conditions must match. Defaults to all of them."},
+			"mode":            {Type: "string", Summary: "'match' (the default) to search for AVUs, 'exists' to search for objects with any AVU with the attribute, or 'missing' to search for objects with no AVU with the attribute. Without an attribute, 'exists' and 'missing' search for objects with or without any metadata at all. With attribute_exact, the attribute must match an AVU's entire attribute, including case. These modes take only the attribute, attribute_exact, and metadata_types arguments."},
+			"value_type":      {Type: "string", Summary: "How to compare the AVU's value: 'string' (the default for values) searches it as text, while 'numeric' and 'date' compare it as a number or a date, in any format the created clause accepts, against a typed version of the value. Ranges are numeric if all of their bounds are numbers, and dates otherwise, unless set."},
 		},
 	}
 )
@@ -36,93 +42,411 @@ type MetadataArgs struct {
 	Attribute      string
 	Value          string
 	Unit           string
-	MetadataTypes  []string `mapstructure:"metadata_types"`
-	AttributeExact bool     `mapstructure:"attribute_exact"`
-	ValueExact     bool     `mapstructure:"value_exact"`
-	UnitExact      bool     `mapstructure:"unit_exact"`
+	MetadataTypes  []string    `mapstructure:"metadata_types"`
+	AttributeExact bool        `mapstructure:"attribute_exact"`
+	ValueExact     bool        `mapstructure:"value_exact"`
+	UnitExact      bool        `mapstructure:"unit_exact"`
+	ValueRange     interface{} `mapstructure:"value_range"`
+	ValueType      string      `mapstructure:"value_type"`
+	Conditions     []MetadataCondition
+	MinMatches     int `mapstructure:"min_matches"`
+	Mode           string
 }
 
-func makeNested(suffix, attr, value, unit string) elastic.Query {
-	inner := elastic.NewBoolQuery()
-	if attr != "" {
-		inner.Must(elastic.NewQueryStringQuery(attr).Field(fmt.Sprintf("metadata.%s.attribute", suffix)))
+// The modes of the metadata clause
+const (
+	matchMode   = "match"
+	existsMode  = "exists"
+	missingMode = "missing"
+)
+
+// mode returns the mode of the clause, checking that only the arguments it takes were passed
+func (a MetadataArgs) mode() (string, error) {
+	switch a.Mode {
+	case "", matchMode:
+		return matchMode, nil
+	case existsMode, missingMode:
+		if a.Value != "" || a.Unit != "" || a.ValueRange != nil || a.ValueType != "" || a.ValueExact || a.UnitExact || len(a.Conditions) > 0 || a.MinMatches != 0 {
+			return "", fmt.Errorf("The %s mode only takes the attribute, attribute_exact, and metadata_types arguments", a.Mode)
+		}
+		return a.Mode, nil
+	}
+	return "", fmt.Errorf("Got a mode of %q, but expected match, exists, or missing", a.Mode)
+}
+
+// MetadataCondition is a single AVU condition of a metadata clause matching several
+type MetadataCondition struct {
+	Attribute      string
+	Value          string
+	Unit           string
+	AttributeExact bool        `mapstructure:"attribute_exact"`
+	ValueExact     bool        `mapstructure:"value_exact"`
+	UnitExact      bool        `mapstructure:"unit_exact"`
+	ValueRange     interface{} `mapstructure:"value_range"`
+	ValueType      string      `mapstructure:"value_type"`
+	SameAVU        *bool       `mapstructure:"same_avu"`
+}
+
+// args returns the condition as the args of a metadata clause matching it alone
+func (c MetadataCondition) args() MetadataArgs {
+	return MetadataArgs{
+		Attribute:      c.Attribute,
+		Value:          c.Value,
+		Unit:           c.Unit,
+		AttributeExact: c.AttributeExact,
+		ValueExact:     c.ValueExact,
+		UnitExact:      c.UnitExact,
+		ValueRange:     c.ValueRange,
+		ValueType:      c.ValueType,
 	}
-	if value != "" {
-		inner.Must(elastic.NewQueryStringQuery(value).Field(fmt.Sprintf("metadata.%s.value", suffix)))
+}
+
+// sameAVU returns whether a single AVU must match the entire condition
+func (c MetadataCondition) sameAVU() bool {
+	return c.SameAVU == nil || *c.SameAVU
+}
+
+// hasAVU returns whether any part of an AVU to match was passed
+func (a MetadataArgs) hasAVU() bool {
+	return a.Attribute != "" || a.Value != "" || a.Unit != "" || a.ValueRange != nil
+}
+
+// split returns an AVU's args as separate args for each of its attribute, value, and unit, so each may match a different AVU
+func (a MetadataArgs) split() []MetadataArgs {
+	var parts []MetadataArgs
+	if a.Attribute != "" {
+		parts = append(parts, MetadataArgs{Attribute: a.Attribute, AttributeExact: a.AttributeExact})
+	}
+	if a.Value != "" || a.ValueRange != nil {
+		parts = append(parts, MetadataArgs{Value: a.Value, ValueExact: a.ValueExact, ValueRange: a.ValueRange, ValueType: a.ValueType})
 	}
-	if unit != "" {
-		inner.Must(elastic.NewQueryStringQuery(unit).Field(fmt.Sprintf("metadata.%s.unit", suffix)))
+	if a.Unit != "" {
+		parts = append(parts, MetadataArgs{Unit: a.Unit, UnitExact: a.UnitExact})
 	}
-	return elastic.NewNestedQuery(fmt.Sprintf("metadata.%s", suffix), inner)
+	return parts
 }
 
-func MetadataProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
-	var realArgs MetadataArgs
-	err := mapstructure.Decode(args, &realArgs)
+// validateConditions checks the conditions and min_matches arguments, returning how many conditions must match
+func validateConditions(realArgs MetadataArgs) (int, error) {
+	if realArgs.hasAVU() || realArgs.ValueType != "" {
+		return 0, errors.New("Cannot pass attribute, value, value_range, value_type, or unit alongside conditions")
+	}
+	for i, c := range realArgs.Conditions {
+		if !c.args().hasAVU() {
+			return 0, fmt.Errorf("Condition %d must provide at least one of attribute, value, value_range, or unit", i+1)
+		}
+	}
+
+	minMatches := realArgs.MinMatches
+	if minMatches == 0 {
+		minMatches = len(realArgs.Conditions)
+	}
+	if minMatches < 0 || minMatches > len(realArgs.Conditions) {
+		return 0, fmt.Errorf("The min_matches must be between 1 and the number of conditions, %d", len(realArgs.Conditions))
+	}
+	return minMatches, nil
+}
+
+const (
+	stringValue  = "string"
+	numericValue = "numeric"
+	dateValue    = "date"
+)
+
+// typedValue is a value or range of values to compare against a typed subfield of the AVU's value
+type typedValue struct {
+	subfield string
+	rb       func(field string) *clauseutils.RangeBuilder
+}
+
+// rangeBoundString converts a value_range bound, which may be passed as a number, to a string
+func rangeBoundString(bound interface{}) (string, error) {
+	switch b := bound.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return b, nil
+	case float64:
+		return strconv.FormatFloat(b, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(b), nil
+	case int64:
+		return strconv.FormatInt(b, 10), nil
+	}
+	return "", fmt.Errorf("Range bounds must be strings or numbers, not %T", bound)
+}
+
+// valueRange returns the value_range argument, if any
+func (a MetadataArgs) valueRange() (*clauseutils.RangeExpression, error) {
+	switch r := a.ValueRange.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		expr, err := clauseutils.ParseRangeExpression(r)
+		return &expr, err
+	case map[string]interface{}:
+		var bounds struct {
+			From          interface{}
+			To            interface{}
+			FromExclusive bool `mapstructure:"from_exclusive"`
+			ToExclusive   bool `mapstructure:"to_exclusive"`
+		}
+		if err := mapstructure.Decode(r, &bounds); err != nil {
+			return nil, err
+		}
+		from, err := rangeBoundString(bounds.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := rangeBoundString(bounds.To)
+		if err != nil {
+			return nil, err
+		}
+		if from == "" && to == "" {
+			return nil, errors.New("The value_range must have a from or a to")
+		}
+		return &clauseutils.RangeExpression{From: from, To: to, FromExclusive: bounds.FromExclusive, ToExclusive: bounds.ToExclusive}, nil
+	}
+	return nil, fmt.Errorf("The value_range must be an object or a string, not %T", a.ValueRange)
+}
+
+// isNumeric returns whether every non-blank bound in expr is a number
+func isNumeric(expr *clauseutils.RangeExpression) bool {
+	for _, bound := range []string{expr.From, expr.To} {
+		if _, err := strconv.ParseFloat(bound, 64); bound != "" && err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTypedBound parses a bound of a numeric or date value, rounding dates as Elasticsearch does for the bound
+func parseTypedBound(ctx context.Context, valueType, bound string, rounding clauseutils.DateRounding) (interface{}, error) {
+	if valueType == numericValue {
+		n, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return nil, fmt.Errorf("The value %q is not a number", bound)
+		}
+		return n, nil
+	}
+	return clauseutils.ParseDate(ctx, bound, rounding)
+}
+
+// typedValueMatch validates the value_range and value_type arguments, returning what to compare against a typed
+// subfield of the value, or nil if the value should be searched as text
+func typedValueMatch(ctx context.Context, realArgs MetadataArgs) (*typedValue, error) {
+	expr, err := realArgs.valueRange()
 	if err != nil {
 		return nil, err
 	}
 
-	if realArgs.Attribute == "" && realArgs.Value == "" && realArgs.Unit == "" {
-		return nil, errors.New("Must provide at least one of attribute, value, or unit")
+	valueType := realArgs.ValueType
+	switch valueType {
+	case "", stringValue, numericValue, dateValue:
+	default:
+		return nil, fmt.Errorf("Got a value type of %q, but expected string, numeric, or date", valueType)
 	}
 
-	var includeIrods, includeCyverse bool
-	if len(realArgs.MetadataTypes) == 0 {
-		includeIrods = true
-		includeCyverse = true
-	} else {
-		for _, t := range realArgs.MetadataTypes {
-			if t == "irods" {
-				includeIrods = true
-			} else if t == "cyverse" {
-				includeCyverse = true
-			} else {
-				return nil, fmt.Errorf("Got a metadata type of %q, but expected irods or cyverse", t)
-			}
+	if expr == nil {
+		if valueType == "" || valueType == stringValue || realArgs.Value == "" {
+			return nil, nil
 		}
+		// a single value matches the whole of its precision, such as a whole day for 2019-06-01
+		expr = &clauseutils.RangeExpression{From: realArgs.Value, To: realArgs.Value}
+	} else if realArgs.Value != "" {
+		return nil, errors.New("Cannot pass both a value and a value_range")
 	}
 
-	finalq := elastic.NewBoolQuery()
+	if valueType == "" {
+		valueType = dateValue
+		if isNumeric(expr) {
+			valueType = numericValue
+		}
+	}
+	if valueType == stringValue {
+		return nil, errors.New("A value_range cannot be compared as a string")
+	}
+
+	var from, to interface{}
+	if expr.From != "" {
+		rounding := clauseutils.RoundDown
+		if expr.FromExclusive {
+			rounding = clauseutils.RoundUp
+		}
+		if from, err = parseTypedBound(ctx, valueType, expr.From, rounding); err != nil {
+			return nil, err
+		}
+	}
+	if expr.To != "" {
+		rounding := clauseutils.RoundUp
+		if expr.ToExclusive {
+			rounding = clauseutils.RoundDown
+		}
+		if to, err = parseTypedBound(ctx, valueType, expr.To, rounding); err != nil {
+			return nil, err
+		}
+	}
 
-	var attr, value, unit string
-	if realArgs.AttributeExact {
-		attr = realArgs.Attribute
-	} else {
-		attr = clauseutils.AddImplicitWildcard(realArgs.Attribute)
+	rb := func(field string) *clauseutils.RangeBuilder {
+		rb := clauseutils.NewRangeBuilder(field)
+		if from != nil {
+			rb.From(from, expr.FromExclusive)
+		}
+		if to != nil {
+			rb.To(to, expr.ToExclusive)
+		}
+		return rb
 	}
-	if realArgs.ValueExact {
-		value = realArgs.Value
-	} else {
-		value = clauseutils.AddImplicitWildcard(realArgs.Value)
+	// checked once here, so building the query for each namespace can't fail
+	if _, err = rb("").Build(); err != nil {
+		return nil, err
 	}
-	if realArgs.UnitExact {
-		unit = realArgs.Unit
-	} else {
-		unit = clauseutils.AddImplicitWildcard(realArgs.Unit)
+	return &typedValue{subfield: valueType, rb: rb}, nil
+}
+
+// fieldQuery creates the query for a single AVU field, or nil if the input is blank. Exact searches match the input as a
+// phrase, with any query_string syntax escaped; inexact searches use the wildcard strategy carried by ctx.
+func fieldQuery(ctx context.Context, field, input string, exact bool) elastic.Query {
+	if input == "" {
+		return nil
 	}
+	if exact {
+		return elastic.NewQueryStringQuery(clauseutils.QuoteQueryString(input)).Field(field)
+	}
+	return clauseutils.WildcardQuery(ctx, field, input)
+}
 
-	if includeIrods {
-		finalq.Should(makeNested("irods", attr, value, unit))
+// makeNestedContext creates the nested query matching a single AVU in one metadata namespace. If typed is set, it is
+// compared against the typed subfield of the value (such as metadata.irods.value.numeric) rather than searching the value as text.
+func makeNestedContext(ctx context.Context, ns clauseutils.MetadataNamespace, args MetadataArgs, typed *typedValue) elastic.Query {
+	inner := elastic.NewBoolQuery()
+	if q := fieldQuery(ctx, ns.Attribute(), args.Attribute, args.AttributeExact); q != nil {
+		inner.Must(q)
+	}
+	if typed != nil {
+		q, _ := typed.rb(fmt.Sprintf("%s.%s", ns.Value(), typed.subfield)).Build()
+		inner.Must(q)
+	} else if q := fieldQuery(ctx, ns.Value(), args.Value, args.ValueExact); q != nil {
+		inner.Must(q)
+	}
+	if q := fieldQuery(ctx, ns.Unit(), args.Unit, args.UnitExact); q != nil {
+		inner.Must(q)
 	}
-	if includeCyverse {
-		finalq.Should(makeNested("cyverse", attr, value, unit))
+	return elastic.NewNestedQuery(ns.NestedPath(), inner)
+}
+
+// attributeExistsQuery creates the query matching objects with any AVU with the attribute, or any AVU at all if it is blank,
+// in any of the namespaces. Exact attributes must match the whole of an AVU's attribute keyword; others use the wildcard
+// strategy carried by ctx, as in match mode.
+func attributeExistsQuery(ctx context.Context, realArgs MetadataArgs, namespaces clauseutils.MetadataNamespaces) elastic.Query {
+	finalq := elastic.NewBoolQuery()
+	for _, namespace := range namespaces {
+		inner := elastic.NewBoolQuery().Must(elastic.NewExistsQuery(namespace.Attribute()))
+		if realArgs.Attribute != "" && realArgs.AttributeExact {
+			inner.Must(elastic.NewTermQuery(namespace.AttributeKeyword(), realArgs.Attribute))
+		} else if q := fieldQuery(ctx, namespace.Attribute(), realArgs.Attribute, false); q != nil {
+			inner.Must(q)
+		}
+		finalq.Should(elastic.NewNestedQuery(namespace.NestedPath(), inner))
+	}
+	return finalq
+}
+
+// metadataNamespaces returns the metadata namespaces to search, according to the metadata_types argument and the namespaces
+// configured in ctx
+func metadataNamespaces(ctx context.Context, realArgs MetadataArgs) (clauseutils.MetadataNamespaces, error) {
+	return clauseutils.MetadataNamespacesOrDefault(ctx).Select(realArgs.MetadataTypes)
+}
+
+// avuQuery creates the query matching a single AVU in any of the namespaces
+func avuQuery(ctx context.Context, realArgs MetadataArgs, namespaces clauseutils.MetadataNamespaces) (elastic.Query, error) {
+	typed, err := typedValueMatch(ctx, realArgs)
+	if err != nil {
+		return nil, err
 	}
 
+	finalq := elastic.NewBoolQuery()
+	for _, namespace := range namespaces {
+		finalq.Should(makeNestedContext(ctx, namespace, realArgs, typed))
+	}
 	return finalq, nil
 }
 
-func MetadataSummary(_ context.Context, args map[string]interface{}) (string, error) {
+// conditionQuery creates the query for one of several conditions, which either a single AVU must match entirely or
+// whose attribute, value, and unit may each match a different AVU
+func conditionQuery(ctx context.Context, condition MetadataCondition, namespaces clauseutils.MetadataNamespaces) (elastic.Query, error) {
+	if condition.sameAVU() {
+		return avuQuery(ctx, condition.args(), namespaces)
+	}
+
+	parts := condition.args().split()
+	if len(parts) == 1 {
+		return avuQuery(ctx, parts[0], namespaces)
+	}
+	query := elastic.NewBoolQuery()
+	for _, part := range parts {
+		q, err := avuQuery(ctx, part, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		query.Must(q)
+	}
+	return query, nil
+}
+
+func MetadataProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs MetadataArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if realArgs.Attribute == "" && realArgs.Value == "" && realArgs.Unit == "" {
-		return "", errors.New("Must provide at least one of attribute, value, or unit")
+	namespaces, err := metadataNamespaces(ctx, realArgs)
+	if err != nil {
+		return nil, err
 	}
 
+	mode, err := realArgs.mode()
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case existsMode:
+		return attributeExistsQuery(ctx, realArgs, namespaces), nil
+	case missingMode:
+		// the negation goes outside the nested queries, since negating within them would match objects with any other AVU
+		return elastic.NewBoolQuery().MustNot(attributeExistsQuery(ctx, realArgs, namespaces)), nil
+	}
+
+	if len(realArgs.Conditions) == 0 {
+		if !realArgs.hasAVU() {
+			return nil, errors.New("Must provide at least one of attribute, value, value_range, unit, or conditions")
+		}
+		return avuQuery(ctx, realArgs, namespaces)
+	}
+
+	minMatches, err := validateConditions(realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var queries []elastic.Query
+	for _, condition := range realArgs.Conditions {
+		q, err := conditionQuery(ctx, condition, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	if minMatches == len(queries) {
+		return elastic.NewBoolQuery().Must(queries...), nil
+	}
+	return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(minMatches), nil
+}
+
+// avuSummary summarizes the attribute, value, and unit to match
+func avuSummary(realArgs MetadataArgs) (string, error) {
 	var a, v, u string
 	if realArgs.Attribute != "" {
 		if realArgs.AttributeExact {
@@ -131,7 +455,17 @@ func MetadataSummary(_ context.Context, args map[string]interface{}) (string, er
 			a = fmt.Sprintf("attr~\"%s\"", realArgs.Attribute)
 		}
 	}
-	if realArgs.Value != "" {
+	valueName := "value"
+	if realArgs.ValueType != "" && realArgs.ValueType != stringValue {
+		valueName = "value:" + realArgs.ValueType
+	}
+	if expr, err := realArgs.valueRange(); err != nil {
+		return "", err
+	} else if expr != nil {
+		v = expr.Summary(valueName)
+	} else if realArgs.Value != "" && valueName != "value" {
+		v = fmt.Sprintf("%s=\"%s\"", valueName, realArgs.Value)
+	} else if realArgs.Value != "" {
 		if realArgs.ValueExact {
 			v = fmt.Sprintf("value=\"%s\"", realArgs.Value)
 		} else {
@@ -145,11 +479,122 @@ func MetadataSummary(_ context.Context, args map[string]interface{}) (string, er
 			u = fmt.Sprintf("unit~\"%s\"", realArgs.Unit)
 		}
 	}
-	avu := strings.Join([]string{a, v, u}, ",")
+	return fmt.Sprintf("(%s)", strings.Join([]string{a, v, u}, ",")), nil
+}
+
+func MetadataSummary(_ context.Context, args map[string]interface{}) (string, error) {
+	var realArgs MetadataArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
 	types := strings.Join(realArgs.MetadataTypes, ",")
-	return fmt.Sprintf("metadata=(%s)(%s)", avu, types), nil
+
+	mode, err := realArgs.mode()
+	if err != nil {
+		return "", err
+	}
+	if mode != matchMode {
+		var attr string
+		if realArgs.Attribute != "" && realArgs.AttributeExact {
+			attr = fmt.Sprintf("attr=\"%s\"", realArgs.Attribute)
+		} else if realArgs.Attribute != "" {
+			attr = fmt.Sprintf("attr~\"%s\"", realArgs.Attribute)
+		}
+		return fmt.Sprintf("metadata=%s(%s)(%s)", mode, attr, types), nil
+	}
+
+	if len(realArgs.Conditions) == 0 {
+		if !realArgs.hasAVU() {
+			return "", errors.New("Must provide at least one of attribute, value, value_range, unit, or conditions")
+		}
+		avu, err := avuSummary(realArgs)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("metadata=%s(%s)", avu, types), nil
+	}
+
+	minMatches, err := validateConditions(realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	conditions := make([]string, len(realArgs.Conditions))
+	for i, condition := range realArgs.Conditions {
+		avu, err := avuSummary(condition.args())
+		if err != nil {
+			return "", err
+		}
+		// conditions that different AVUs may match are marked with a *
+		if !condition.sameAVU() {
+			avu = "*" + avu
+		}
+		conditions[i] = avu
+	}
+
+	quantifier := "all"
+	if minMatches < len(conditions) {
+		quantifier = fmt.Sprintf("%dof", minMatches)
+	}
+	return fmt.Sprintf("metadata=%s[%s](%s)", quantifier, strings.Join(conditions, ","), types), nil
+}
+
+// avuCost estimates the cost of matching a single AVU in a number of namespaces
+func avuCost(ctx context.Context, realArgs MetadataArgs, namespaces int) clause.ClauseCost {
+	valueCost := clauseutils.WildcardSearchCost(ctx, realArgs.Value, realArgs.ValueExact)
+	if realArgs.ValueRange != nil || (realArgs.ValueType != "" && realArgs.ValueType != stringValue) {
+		valueCost = clause.ClauseCost{Cost: clauseutils.RangeCost, Terms: 1}
+	}
+
+	var costs []clause.ClauseCost
+	for i := 0; i < namespaces; i++ {
+		costs = append(costs,
+			clause.ClauseCost{Cost: clauseutils.NestedCost},
+			clauseutils.WildcardSearchCost(ctx, realArgs.Attribute, realArgs.AttributeExact),
+			valueCost,
+			clauseutils.WildcardSearchCost(ctx, realArgs.Unit, realArgs.UnitExact),
+		)
+	}
+	return clauseutils.AddCosts(costs...)
+}
+
+func MetadataCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs MetadataArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	namespaces := len(realArgs.MetadataTypes)
+	if namespaces == 0 {
+		namespaces = len(clauseutils.MetadataNamespacesOrDefault(ctx))
+	}
+
+	if realArgs.Mode == existsMode || realArgs.Mode == missingMode {
+		attrArgs := MetadataArgs{Attribute: realArgs.Attribute, AttributeExact: realArgs.AttributeExact}
+		return avuCost(ctx, attrArgs, namespaces), nil
+	}
+
+	if len(realArgs.Conditions) == 0 {
+		return avuCost(ctx, realArgs, namespaces), nil
+	}
+
+	var costs []clause.ClauseCost
+	for _, condition := range realArgs.Conditions {
+		if condition.sameAVU() {
+			costs = append(costs, avuCost(ctx, condition.args(), namespaces))
+			continue
+		}
+		for _, part := range condition.args().split() {
+			costs = append(costs, avuCost(ctx, part, namespaces))
+		}
+	}
+	return clauseutils.AddCosts(costs...), nil
 }
 
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseTypeSummarized(typeKey, MetadataProcessor, documentation, MetadataSummary)
+	qd.SetClauseCoster(typeKey, MetadataCost)
 }
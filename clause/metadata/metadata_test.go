@@ -3,38 +3,35 @@ package metadata
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
 	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
 )
 
-type MakeNested struct {
-	Nested struct {
-		Path      string
-		ScoreMode string `mapstructure:"score_mode"`
-		Query     struct {
-			Bool struct {
-				Must interface{}
-			}
-		}
-	}
-}
-
-type QueryStringQ struct {
-	QueryString struct {
-		Query  string
-		Fields []string
-	} `mapstructure:"query_string"`
-}
-
 type MainMetadataQ struct {
 	Bool struct {
 		Should interface{}
 	}
 }
 
+// exactAVU creates the expected nested query matching an AVU exactly in the irods namespace
+func exactAVU(attr, value, unit string) elastic.Query {
+	inner := elastic.NewBoolQuery()
+	for _, field := range []struct{ name, input string }{{"attribute", attr}, {"value", value}, {"unit", unit}} {
+		if field.input != "" {
+			inner.Must(elastic.NewQueryStringQuery(clauseutils.QuoteQueryString(field.input)).Field("metadata.irods." + field.name))
+		}
+	}
+	return elastic.NewNestedQuery("metadata.irods", inner)
+}
+
 func TestNested(t *testing.T) {
+	irods, _ := clauseutils.DefaultMetadataNamespaces.Lookup("irods")
 	cases := []struct {
 		attribute string
 		value     string
@@ -44,61 +41,41 @@ func TestNested(t *testing.T) {
 		{value: "testv"},
 		{unit: "testu"},
 		{attribute: "testav-a", value: "testav-v"},
+		{attribute: "test a", value: "test:v", unit: "test(u)"},
 	}
 
 	for _, c := range cases {
 		t.Run(fmt.Sprintf("%+v,%+v,%+v", c.attribute, c.value, c.unit), func(t *testing.T) {
-			v := makeNested("irods", c.attribute, c.value, c.unit)
-
-			source, err := v.Source()
+			expected, err := exactAVU(c.attribute, c.value, c.unit).Source()
 			if err != nil {
-				t.Errorf("Source get failed with error: %q", err)
+				t.Fatalf("Source get on expected query failed with error: %q", err)
 			}
 
-			var decoded MakeNested
-			err = mapstructure.Decode(source, &decoded)
-
+			args := MetadataArgs{Attribute: c.attribute, AttributeExact: true, Value: c.value, ValueExact: true, Unit: c.unit, UnitExact: true}
+			source, err := makeNestedContext(context.Background(), irods, args, nil).Source()
 			if err != nil {
-				t.Errorf("Decode failed with error: %q", err)
+				t.Fatalf("Source get failed with error: %q", err)
 			}
-
-			if decoded.Nested.Path != "metadata.irods" {
-				t.Error("Nested path is not 'metadata.irods'")
+			if !reflect.DeepEqual(source, expected) {
+				t.Errorf("makeNestedContext gave %+v, expected %+v", source, expected)
 			}
 
-			if c.attribute != "" && c.value != "" {
-				mq, ok := decoded.Nested.Query.Bool.Must.([]interface{})
-				if !ok {
-					t.Error("Could not grab an array from 'must'")
-				}
-
-				var first QueryStringQ
-				err = mapstructure.Decode(mq[0], &first)
-				if err != nil {
-					t.Errorf("Decode failed with error: %q", err)
-				}
-
-				if first.QueryString.Fields[0] != "metadata.irods.attribute" {
-					t.Error("First nested query had wrong field")
-				}
-
-				if first.QueryString.Query != c.attribute {
-					t.Error("First nested query had wrong query string")
-				}
-
-				var second QueryStringQ
-				err = mapstructure.Decode(mq[1], &second)
-				if err != nil {
-					t.Errorf("Decode failed with error: %q", err)
-				}
-
-				if second.QueryString.Fields[0] != "metadata.irods.value" {
-					t.Error("Second nested query had wrong field")
-				}
-
-				if second.QueryString.Query != c.value {
-					t.Error("Second nested query had wrong query string")
-				}
+			query, err := MetadataProcessor(context.Background(), map[string]interface{}{
+				"attribute": c.attribute, "attribute_exact": true,
+				"value": c.value, "value_exact": true,
+				"unit": c.unit, "unit_exact": true,
+				"metadata_types": []string{"irods"},
+			})
+			if err != nil {
+				t.Fatalf("MetadataProcessor failed with error: %q", err)
+			}
+			source, err = query.Source()
+			if err != nil {
+				t.Fatalf("Source get failed with error: %q", err)
+			}
+			expected, _ = elastic.NewBoolQuery().Should(exactAVU(c.attribute, c.value, c.unit)).Source()
+			if !reflect.DeepEqual(source, expected) {
+				t.Errorf("MetadataProcessor gave %+v, expected %+v", source, expected)
 			}
 		})
 	}
@@ -201,3 +178,302 @@ func TestMetadataProcessor(t *testing.T) {
 		})
 	}
 }
+
+func TestMetadataValueRange(t *testing.T) {
+	day := func(y int, m time.Month, d int) int64 {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+	}
+	nested := func(valueQuery elastic.Query) elastic.Query {
+		return elastic.NewBoolQuery().Should(elastic.NewNestedQuery("metadata.irods", elastic.NewBoolQuery().Must(
+			elastic.NewQueryStringQuery(`"temperature"`).Field("metadata.irods.attribute"),
+			valueQuery,
+		)))
+	}
+
+	cases := []struct {
+		name      string
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			name:     "numeric object",
+			args:     map[string]interface{}{"value_range": map[string]interface{}{"from": float64(20), "to": float64(30)}},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.numeric").Gte(20.0).Lte(30.0)),
+		},
+		{
+			name:     "numeric shorthand",
+			args:     map[string]interface{}{"value_range": "> 20.5"},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.numeric").Gt(20.5)),
+		},
+		{
+			name:     "date object",
+			args:     map[string]interface{}{"value_range": map[string]interface{}{"from": "2019-06"}},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.date").Gte(day(2019, 6, 1))),
+		},
+		{
+			name:     "date exclusive",
+			args:     map[string]interface{}{"value_range": map[string]interface{}{"from": "2019-06", "from_exclusive": true}},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.date").Gt(day(2019, 7, 1) - 1)),
+		},
+		{
+			name:     "numeric as date",
+			args:     map[string]interface{}{"value_range": "2019..2020", "value_type": "date"},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.date").Gte(day(2019, 1, 1)).Lte(day(2021, 1, 1) - 1)),
+		},
+		{
+			name:     "typed numeric value",
+			args:     map[string]interface{}{"value": "25", "value_type": "numeric"},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.numeric").Gte(25.0).Lte(25.0)),
+		},
+		{
+			name:     "typed date value",
+			args:     map[string]interface{}{"value": "2019-06-01", "value_type": "date"},
+			expected: nested(elastic.NewRangeQuery("metadata.irods.value.date").Gte(day(2019, 6, 1)).Lte(day(2019, 6, 2) - 1)),
+		},
+		{
+			name:     "string value",
+			args:     map[string]interface{}{"value": "25", "value_type": "string", "value_exact": true},
+			expected: nested(elastic.NewQueryStringQuery(`"25"`).Field("metadata.irods.value")),
+		},
+		{
+			name:     "string value with query syntax",
+			args:     map[string]interface{}{"value": "25 OR *", "value_exact": true},
+			expected: nested(elastic.NewQueryStringQuery(`"25 \OR \*"`).Field("metadata.irods.value")),
+		},
+		{name: "value and range", args: map[string]interface{}{"value": "25", "value_range": "20..30"}, shouldErr: true},
+		{name: "inverted", args: map[string]interface{}{"value_range": "30..20"}, shouldErr: true},
+		{name: "not a number", args: map[string]interface{}{"value_range": "warm..hot", "value_type": "numeric"}, shouldErr: true},
+		{name: "string range", args: map[string]interface{}{"value_range": "20..30", "value_type": "string"}, shouldErr: true},
+		{name: "unknown type", args: map[string]interface{}{"value": "25", "value_type": "integer"}, shouldErr: true},
+		{name: "empty object", args: map[string]interface{}{"value_range": map[string]interface{}{}}, shouldErr: true},
+		{name: "bad bound", args: map[string]interface{}{"value_range": map[string]interface{}{"from": true}}, shouldErr: true},
+		{name: "bad range", args: map[string]interface{}{"value_range": 25}, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.args["attribute"] = "temperature"
+			c.args["attribute_exact"] = true
+			c.args["metadata_types"] = []string{"irods"}
+
+			query, err := MetadataProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("MetadataProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("MetadataProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataConditions(t *testing.T) {
+	avu := func(attr, value string) elastic.Query {
+		return elastic.NewBoolQuery().Should(exactAVU(attr, value, ""))
+	}
+	condition := func(attr, value string, sameAVU bool) map[string]interface{} {
+		return map[string]interface{}{"attribute": attr, "attribute_exact": true, "value": value, "value_exact": true, "same_avu": sameAVU}
+	}
+
+	cases := []struct {
+		name      string
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			name: "all on the same AVU",
+			args: map[string]interface{}{"conditions": []interface{}{condition("species", "human", true), condition("tissue", "liver", true)}},
+			expected: elastic.NewBoolQuery().Must(
+				avu("species", "human"),
+				avu("tissue", "liver"),
+			),
+		},
+		{
+			name: "across AVUs",
+			args: map[string]interface{}{"conditions": []interface{}{condition("species", "human", false)}},
+			expected: elastic.NewBoolQuery().Must(elastic.NewBoolQuery().Must(
+				avu("species", ""),
+				avu("", "human"),
+			)),
+		},
+		{
+			name: "min matches",
+			args: map[string]interface{}{"conditions": []interface{}{condition("species", "human", true), condition("tissue", "liver", true)}, "min_matches": 1},
+			expected: elastic.NewBoolQuery().Should(
+				avu("species", "human"),
+				avu("tissue", "liver"),
+			).MinimumNumberShouldMatch(1),
+		},
+		{name: "min matches too high", args: map[string]interface{}{"conditions": []interface{}{condition("species", "human", true)}, "min_matches": 2}, shouldErr: true},
+		{name: "negative min matches", args: map[string]interface{}{"conditions": []interface{}{condition("species", "human", true)}, "min_matches": -1}, shouldErr: true},
+		{name: "empty condition", args: map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"same_avu": false}}}, shouldErr: true},
+		{name: "attribute alongside conditions", args: map[string]interface{}{"attribute": "species", "conditions": []interface{}{condition("tissue", "liver", true)}}, shouldErr: true},
+		{name: "bad condition", args: map[string]interface{}{"conditions": []interface{}{"species"}}, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.args["metadata_types"] = []string{"irods"}
+
+			query, err := MetadataProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("MetadataProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("MetadataProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Fatalf("Source failed with error: %q", err)
+				}
+				expected, _ := c.expected.Source()
+				if !reflect.DeepEqual(source, expected) {
+					t.Errorf("Got %+v, expected %+v", source, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataExists(t *testing.T) {
+	exists := func(namespace string, attrQuery elastic.Query) elastic.Query {
+		inner := elastic.NewBoolQuery().Must(elastic.NewExistsQuery("metadata." + namespace + ".attribute"))
+		if attrQuery != nil {
+			inner.Must(attrQuery)
+		}
+		return elastic.NewNestedQuery("metadata."+namespace, inner)
+	}
+
+	cases := []struct {
+		name      string
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			name:     "exists",
+			args:     map[string]interface{}{"mode": "exists", "attribute": "species", "attribute_exact": true, "metadata_types": []string{"irods"}},
+			expected: elastic.NewBoolQuery().Should(exists("irods", elastic.NewTermQuery("metadata.irods.attribute.keyword", "species"))),
+		},
+		{
+			name: "missing",
+			args: map[string]interface{}{"mode": "missing", "attribute": "species", "attribute_exact": true},
+			expected: elastic.NewBoolQuery().MustNot(elastic.NewBoolQuery().Should(
+				exists("irods", elastic.NewTermQuery("metadata.irods.attribute.keyword", "species")),
+				exists("cyverse", elastic.NewTermQuery("metadata.cyverse.attribute.keyword", "species")),
+			)),
+		},
+		{
+			name:     "inexact attribute",
+			args:     map[string]interface{}{"mode": "exists", "attribute": "spec", "metadata_types": []string{"cyverse"}},
+			expected: elastic.NewBoolQuery().Should(exists("cyverse", elastic.NewQueryStringQuery("*spec*").Field("metadata.cyverse.attribute"))),
+		},
+		{
+			name:     "no metadata at all",
+			args:     map[string]interface{}{"mode": "missing", "metadata_types": []string{"irods"}},
+			expected: elastic.NewBoolQuery().MustNot(elastic.NewBoolQuery().Should(exists("irods", nil))),
+		},
+		{name: "value", args: map[string]interface{}{"mode": "exists", "attribute": "species", "value": "human"}, shouldErr: true},
+		{name: "conditions", args: map[string]interface{}{"mode": "missing", "conditions": []interface{}{map[string]interface{}{"attribute": "species"}}}, shouldErr: true},
+		{name: "unknown mode", args: map[string]interface{}{"mode": "absent", "attribute": "species"}, shouldErr: true},
+		{name: "value_exact", args: map[string]interface{}{"mode": "exists", "attribute": "species", "value_exact": true}, shouldErr: true},
+		{name: "unit_exact", args: map[string]interface{}{"mode": "missing", "attribute": "species", "unit_exact": true}, shouldErr: true},
+		{name: "min_matches", args: map[string]interface{}{"mode": "exists", "attribute": "species", "min_matches": 1}, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := MetadataProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("MetadataProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("MetadataProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Fatalf("Source failed with error: %q", err)
+				}
+				expected, _ := c.expected.Source()
+				if !reflect.DeepEqual(source, expected) {
+					t.Errorf("Got %+v, expected %+v", source, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataNamespaces(t *testing.T) {
+	ontology := clauseutils.MetadataNamespace{Name: "ontology", Path: "annotations", AttributeField: "annotations.term", ValueField: "annotations.label", UnitField: "annotations.source"}
+	ctx := clauseutils.WithMetadataNamespaces(context.Background(), append(clauseutils.MetadataNamespaces{ontology}, clauseutils.DefaultMetadataNamespaces...))
+
+	query, err := MetadataProcessor(ctx, map[string]interface{}{"attribute": "GO:0008150", "attribute_exact": true})
+	if err != nil {
+		t.Fatalf("MetadataProcessor failed with error: %q", err)
+	}
+	expected := elastic.NewBoolQuery().Should(
+		elastic.NewNestedQuery("annotations", elastic.NewBoolQuery().Must(elastic.NewQueryStringQuery(`"GO\:0008150"`).Field("annotations.term"))),
+		elastic.NewNestedQuery("metadata.irods", elastic.NewBoolQuery().Must(elastic.NewQueryStringQuery(`"GO\:0008150"`).Field("metadata.irods.attribute"))),
+		elastic.NewNestedQuery("metadata.cyverse", elastic.NewBoolQuery().Must(elastic.NewQueryStringQuery(`"GO\:0008150"`).Field("metadata.cyverse.attribute"))),
+	)
+	source, _ := query.Source()
+	expectedSource, _ := expected.Source()
+	if !reflect.DeepEqual(source, expectedSource) {
+		t.Errorf("Got %+v, expected %+v", source, expectedSource)
+	}
+
+	if _, err := MetadataProcessor(ctx, map[string]interface{}{"attribute": "species", "metadata_types": []string{"ontology", "irods"}}); err != nil {
+		t.Errorf("MetadataProcessor failed with error: %q", err)
+	}
+	if _, err := MetadataProcessor(context.Background(), map[string]interface{}{"attribute": "species", "metadata_types": []string{"ontology"}}); err == nil {
+		t.Error("MetadataProcessor should have failed for a namespace that isn't configured")
+	}
+
+	cost, err := MetadataCost(ctx, map[string]interface{}{"attribute": "species", "attribute_exact": true})
+	if err != nil {
+		t.Fatalf("MetadataCost failed with error: %q", err)
+	}
+	defaultCost, _ := MetadataCost(context.Background(), map[string]interface{}{"attribute": "species", "attribute_exact": true})
+	if cost.Cost <= defaultCost.Cost {
+		t.Errorf("Searching three namespaces cost %v, no more than the %v of searching two", cost, defaultCost)
+	}
+}
+
+func TestMetadataSummary(t *testing.T) {
+	cases := []struct {
+		args     map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"attribute": "species", "attribute_exact": true, "value": "human"}, `metadata=(attr="species",value~"human",)()`},
+		{map[string]interface{}{"attribute": "temperature", "value_range": map[string]interface{}{"from": float64(20), "to": float64(30)}}, `metadata=(attr~"temperature",value=20..30,)()`},
+		{map[string]interface{}{"value_range": "> 2019", "value_type": "date"}, `metadata=(,value:date>2019,)()`},
+		{map[string]interface{}{"value": "25", "value_type": "numeric", "metadata_types": []string{"irods"}}, `metadata=(,value:numeric="25",)(irods)`},
+		{map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"attribute": "species", "value": "human"}, map[string]interface{}{"attribute": "tissue", "value": "liver", "same_avu": false}}}, `metadata=all[(attr~"species",value~"human",),*(attr~"tissue",value~"liver",)]()`},
+		{map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"attribute": "species"}, map[string]interface{}{"attribute": "tissue"}}, "min_matches": 1}, `metadata=1of[(attr~"species",,),(attr~"tissue",,)]()`},
+		{map[string]interface{}{"mode": "exists", "attribute": "species", "attribute_exact": true}, `metadata=exists(attr="species")()`},
+		{map[string]interface{}{"mode": "missing", "metadata_types": []string{"cyverse"}}, `metadata=missing()(cyverse)`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := MetadataSummary(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("MetadataSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
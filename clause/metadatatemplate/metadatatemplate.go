@@ -0,0 +1,190 @@
+package metadatatemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clause/metadata"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	typeKey = "metadata_template"
+)
+
+var (
+	documentation = clause.ClauseDocumentation{
+		Summary: "Searches metadata filled in from a metadata template, in the configured template metadata namespace (by default 'cyverse'), validating attributes and values against the template and searching each according to its type",
+		Args: map[string]clause.ClauseArgumentDocumentation{
+			"template_id": {Type: "string", Summary: "The ID of the metadata template"},
+			"attributes": {Type: "map[string]string", Summary: "The values to search for, by the name of the template attribute. Enum values must be one of the template's values, and Boolean values true or false; these, and URL/URI values, are matched exactly. " +
+				"Number, Integer, Date, and Timestamp values may also be ranges. " + clauseutils.RangeExpressionDocumentation + " Values of other types are searched as text, with implicit wildcards. A blank value matches any value of the attribute."},
+		},
+	}
+)
+
+type MetadataTemplateArgs struct {
+	TemplateID string `mapstructure:"template_id"`
+	Attributes map[string]string
+}
+
+// attributeNames returns the names of the attributes to search, sorted so queries and summaries are stable
+func (a MetadataTemplateArgs) attributeNames() []string {
+	names := make([]string, 0, len(a.Attributes))
+	for name := range a.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTemplate fetches the template named by the args from the TemplateProvider carried by ctx
+func lookupTemplate(ctx context.Context, realArgs MetadataTemplateArgs) (*clauseutils.MetadataTemplate, error) {
+	if realArgs.TemplateID == "" {
+		return nil, errors.New("Must provide a template_id")
+	}
+	if len(realArgs.Attributes) == 0 {
+		return nil, errors.New("Must provide at least one attribute")
+	}
+
+	provider, ok := clauseutils.TemplateProviderFromContext(ctx)
+	if !ok {
+		return nil, errors.New("No metadata template provider is configured, cannot search metadata templates")
+	}
+	return provider.Template(ctx, realArgs.TemplateID)
+}
+
+// attributeCondition creates the metadata clause condition matching a value of a template attribute, according to its type.
+// The attribute and exact values are matched by the metadata clause as whole phrases, whatever query_string syntax they contain.
+func attributeCondition(attr *clauseutils.TemplateAttribute, value string) (map[string]interface{}, error) {
+	condition := map[string]interface{}{"attribute": attr.Name, "attribute_exact": true}
+	if value == "" {
+		return condition, nil
+	}
+
+	switch attr.Type {
+	case clauseutils.TemplateEnum:
+		found := false
+		for _, v := range attr.Enum {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Got a value of %q for %s, but expected one of %s", value, attr.Name, strings.Join(attr.Enum, ", "))
+		}
+		condition["value"] = value
+		condition["value_exact"] = true
+	case clauseutils.TemplateBoolean:
+		lower := strings.ToLower(value)
+		if lower != "true" && lower != "false" {
+			return nil, fmt.Errorf("Got a value of %q for %s, but expected true or false", value, attr.Name)
+		}
+		condition["value"] = lower
+		condition["value_exact"] = true
+	case clauseutils.TemplateURL:
+		condition["value"] = value
+		condition["value_exact"] = true
+	case clauseutils.TemplateNumber, clauseutils.TemplateInteger:
+		condition["value_range"] = value
+		condition["value_type"] = "numeric"
+	case clauseutils.TemplateDate, clauseutils.TemplateTimestamp:
+		condition["value_range"] = value
+		condition["value_type"] = "date"
+	default:
+		condition["value"] = value
+	}
+	return condition, nil
+}
+
+// metadataArgs translates the args into those of a metadata clause searching the template's attributes in the template
+// metadata namespace
+func metadataArgs(ctx context.Context, realArgs MetadataTemplateArgs) (map[string]interface{}, error) {
+	template, err := lookupTemplate(ctx, realArgs)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := clauseutils.TemplateNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []interface{}
+	for _, name := range realArgs.attributeNames() {
+		attr, ok := template.Attribute(name)
+		if !ok {
+			return nil, fmt.Errorf("The metadata template %q has no attribute %q", realArgs.TemplateID, name)
+		}
+		condition, err := attributeCondition(attr, realArgs.Attributes[name])
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return map[string]interface{}{"conditions": conditions, "metadata_types": []string{namespace.Name}}, nil
+}
+
+func MetadataTemplateProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	var realArgs MetadataTemplateArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	mdArgs, err := metadataArgs(ctx, realArgs)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.MetadataProcessor(ctx, mdArgs)
+}
+
+func MetadataTemplateSummary(_ context.Context, args map[string]interface{}) (string, error) {
+	var realArgs MetadataTemplateArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if realArgs.TemplateID == "" {
+		return "", errors.New("Must provide a template_id")
+	}
+
+	var attrs []string
+	for _, name := range realArgs.attributeNames() {
+		value := realArgs.Attributes[name]
+		if value == "" {
+			attrs = append(attrs, fmt.Sprintf("\"%s\"", name))
+		} else {
+			attrs = append(attrs, fmt.Sprintf("\"%s\"=\"%s\"", name, value))
+		}
+	}
+	return fmt.Sprintf("metadata_template[%s](%s)", realArgs.TemplateID, strings.Join(attrs, ",")), nil
+}
+
+func MetadataTemplateCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs MetadataTemplateArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	mdArgs, err := metadataArgs(ctx, realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+	return metadata.MetadataCost(ctx, mdArgs)
+}
+
+func Register(qd *querydsl.QueryDSL) {
+	qd.AddClauseTypeSummarized(typeKey, MetadataTemplateProcessor, documentation, MetadataTemplateSummary)
+	qd.SetClauseCoster(typeKey, MetadataTemplateCost)
+}
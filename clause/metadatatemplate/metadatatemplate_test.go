@@ -0,0 +1,198 @@
+package metadatatemplate
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clause/metadata"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+const templateID = "40ac191f-bb36-4f4e-85fb-8b50abec8e10"
+
+func testContext(t *testing.T) context.Context {
+	provider, err := clauseutils.LoadJSONTemplateProvider("testdata/templates.json")
+	if err != nil {
+		t.Fatalf("Could not load test templates: %q", err)
+	}
+	return clauseutils.WithTemplateProvider(context.Background(), provider)
+}
+
+func TestMetadataTemplateProcessor(t *testing.T) {
+	ctx := testContext(t)
+	condition := func(c map[string]interface{}) elastic.Query {
+		c["metadata_types"] = []string{"cyverse"}
+		q, err := metadata.MetadataProcessor(ctx, c)
+		if err != nil {
+			t.Fatalf("MetadataProcessor failed with error: %q", err)
+		}
+		return q
+	}
+
+	cases := []struct {
+		name       string
+		templateID string
+		attributes map[string]interface{}
+		expected   elastic.Query
+		shouldErr  bool
+	}{
+		{
+			name:       "typed attributes",
+			templateID: templateID,
+			attributes: map[string]interface{}{"tissue": "liver", "temperature": "20..30", "species": "human"},
+			expected: elastic.NewBoolQuery().Must(
+				condition(map[string]interface{}{"attribute": "species", "attribute_exact": true, "value": "human"}),
+				condition(map[string]interface{}{"attribute": "temperature", "attribute_exact": true, "value_range": "20..30", "value_type": "numeric"}),
+				condition(map[string]interface{}{"attribute": "tissue", "attribute_exact": true, "value": "liver", "value_exact": true}),
+			),
+		},
+		{
+			name:       "dates, booleans, and urls",
+			templateID: templateID,
+			attributes: map[string]interface{}{"collection_date": "2019", "public": "True", "protocol": "https://example.org/protocol"},
+			expected: elastic.NewBoolQuery().Must(
+				condition(map[string]interface{}{"attribute": "collection_date", "attribute_exact": true, "value_range": "2019", "value_type": "date"}),
+				condition(map[string]interface{}{"attribute": "protocol", "attribute_exact": true, "value": "https://example.org/protocol", "value_exact": true}),
+				condition(map[string]interface{}{"attribute": "public", "attribute_exact": true, "value": "true", "value_exact": true}),
+			),
+		},
+		{
+			name:       "any value",
+			templateID: templateID,
+			attributes: map[string]interface{}{"tissue": ""},
+			expected:   elastic.NewBoolQuery().Must(condition(map[string]interface{}{"attribute": "tissue", "attribute_exact": true})),
+		},
+		{name: "unknown enum value", templateID: templateID, attributes: map[string]interface{}{"tissue": "lung"}, shouldErr: true},
+		{name: "not a boolean", templateID: templateID, attributes: map[string]interface{}{"public": "yes"}, shouldErr: true},
+		{name: "not a number", templateID: templateID, attributes: map[string]interface{}{"temperature": "warm"}, shouldErr: true},
+		{name: "unknown attribute", templateID: templateID, attributes: map[string]interface{}{"color": "red"}, shouldErr: true},
+		{name: "unknown template", templateID: "missing", attributes: map[string]interface{}{"tissue": "liver"}, shouldErr: true},
+		{name: "no template", attributes: map[string]interface{}{"tissue": "liver"}, shouldErr: true},
+		{name: "no attributes", templateID: templateID, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args := map[string]interface{}{"template_id": c.templateID, "attributes": c.attributes}
+			query, err := MetadataTemplateProcessor(ctx, args)
+			if c.shouldErr && err == nil {
+				t.Errorf("MetadataTemplateProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("MetadataTemplateProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Fatalf("Source failed with error: %q", err)
+				}
+				expected, _ := c.expected.Source()
+				if !reflect.DeepEqual(source, expected) {
+					t.Errorf("Got %+v, expected %+v", source, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataTemplateExactValues(t *testing.T) {
+	cases := []struct {
+		attribute         string
+		value             string
+		expectedAttribute string
+		expectedValue     string
+	}{
+		{
+			"protocol", "https://example.org/protocol",
+			`{"query_string":{"fields":["metadata.cyverse.attribute"],"query":"\"protocol\""}}`,
+			`{"query_string":{"fields":["metadata.cyverse.value"],"query":"\"https\\:\\/\\/example.org\\/protocol\""}}`,
+		},
+		{
+			"tissue", "smooth muscle (gut)",
+			`{"query_string":{"fields":["metadata.cyverse.attribute"],"query":"\"tissue\""}}`,
+			`{"query_string":{"fields":["metadata.cyverse.value"],"query":"\"smooth muscle \\(gut\\)\""}}`,
+		},
+		{
+			"depth (m)", "10..20",
+			`{"query_string":{"fields":["metadata.cyverse.attribute"],"query":"\"depth \\(m\\)\""}}`,
+			`{"range":{"metadata.cyverse.value.numeric":{"from":10,"include_lower":true,"include_upper":true,"to":20}}}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.attribute, func(t *testing.T) {
+			args := map[string]interface{}{"template_id": templateID, "attributes": map[string]interface{}{c.attribute: c.value}}
+			query, err := MetadataTemplateProcessor(testContext(t), args)
+			if err != nil {
+				t.Fatalf("MetadataTemplateProcessor failed with error: %q", err)
+			}
+			source, err := query.Source()
+			if err != nil {
+				t.Fatalf("Source failed with error: %q", err)
+			}
+
+			// all -> any namespace -> nested -> all of the attribute and the value
+			nested := source.(map[string]interface{})["bool"].(map[string]interface{})["must"].(map[string]interface{})["bool"].(map[string]interface{})["should"].(map[string]interface{})["nested"]
+			must := nested.(map[string]interface{})["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"].([]interface{})
+			for i, expected := range []string{c.expectedAttribute, c.expectedValue} {
+				queryJSON, err := json.Marshal(must[i])
+				if err != nil {
+					t.Fatalf("Marshal failed with error: %q", err)
+				}
+				if string(queryJSON) != expected {
+					t.Errorf("Got query %s, expected %s", queryJSON, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataTemplateNoProvider(t *testing.T) {
+	args := map[string]interface{}{"template_id": templateID, "attributes": map[string]interface{}{"tissue": "liver"}}
+	if _, err := MetadataTemplateProcessor(context.Background(), args); err == nil {
+		t.Error("MetadataTemplateProcessor should have failed without a template provider")
+	}
+}
+
+func TestMetadataTemplateNamespace(t *testing.T) {
+	templates := clauseutils.MetadataNamespace{Name: "templates", Path: "template_avus"}
+	ctx := clauseutils.WithMetadataNamespaces(testContext(t), append(clauseutils.MetadataNamespaces{templates}, clauseutils.DefaultMetadataNamespaces...))
+	args := map[string]interface{}{"template_id": templateID, "attributes": map[string]interface{}{"species": ""}}
+
+	query, err := MetadataTemplateProcessor(clauseutils.WithTemplateNamespace(ctx, "templates"), args)
+	if err != nil {
+		t.Fatalf("MetadataTemplateProcessor failed with error: %q", err)
+	}
+	expected, err := metadata.MetadataProcessor(ctx, map[string]interface{}{
+		"conditions":     []interface{}{map[string]interface{}{"attribute": "species", "attribute_exact": true}},
+		"metadata_types": []string{"templates"},
+	})
+	if err != nil {
+		t.Fatalf("MetadataProcessor failed with error: %q", err)
+	}
+	source, _ := query.Source()
+	expectedSource, _ := expected.Source()
+	if !reflect.DeepEqual(source, expectedSource) {
+		t.Errorf("Got %+v, expected %+v", source, expectedSource)
+	}
+
+	if _, err := MetadataTemplateProcessor(clauseutils.WithTemplateNamespace(ctx, "unknown"), args); err == nil {
+		t.Error("MetadataTemplateProcessor should have failed with an unknown template namespace")
+	}
+	if _, err := MetadataTemplateProcessor(clauseutils.WithMetadataNamespaces(testContext(t), clauseutils.MetadataNamespaces{templates}), args); err == nil {
+		t.Error("MetadataTemplateProcessor should have failed without the default template namespace")
+	}
+}
+
+func TestMetadataTemplateSummary(t *testing.T) {
+	args := map[string]interface{}{"template_id": templateID, "attributes": map[string]interface{}{"tissue": "liver", "temperature": ">= 20", "species": ""}}
+	expected := `metadata_template[` + templateID + `]("species","temperature"=">= 20","tissue"="liver")`
+	summary, err := MetadataTemplateSummary(context.Background(), args)
+	if err != nil {
+		t.Errorf("MetadataTemplateSummary failed with error: %q", err)
+	}
+	if summary != expected {
+		t.Errorf("Got '%s' from summarize, not '%s'", summary, expected)
+	}
+}
@@ -3,6 +3,7 @@ package owner
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
@@ -19,32 +20,117 @@ var (
 	documentation = clause.ClauseDocumentation{
 		Summary: "Searches based on an object's owner(s)",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"owner": {Type: "string", Summary: "The owner to search for. If it includes a # character, it will be searched exactly, otherwise the zone will be wildcarded."},
+			"owner":  {Type: "string", Summary: "The owner to search for. If it includes a # character, it will be searched exactly, otherwise it will be qualified by the configured user resolver, which by default wildcards the zone."},
+			"groups": {Type: "[]string", Summary: "Groups whose members should also be searched for as owners. Groups are expanded to their members' usernames using the configured group resolver, and those usernames are handled as with 'owner'."},
 		},
 	}
 )
 
 type OwnerArgs struct {
-	Owner string
+	Owner  string
+	Groups []string
 }
 
-func OwnerProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+// resolvedOwnerQuery matches any of a set of owners using the UserResolver carried by ctx, preferring exact terms over wildcards
+func resolvedOwnerQuery(ctx context.Context, owners []string) (elastic.Query, error) {
+	var terms []string
+	var wildcards []elastic.Query
+	for _, owner := range owners {
+		resolved, err := clauseutils.ResolveUser(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, resolved.Terms...)
+		if resolved.Wildcard != "" {
+			wildcards = append(wildcards, elastic.NewWildcardQuery("userPermissions.user", resolved.Wildcard))
+		}
+	}
+
+	if len(terms) == 0 && len(wildcards) == 0 {
+		return nil, fmt.Errorf("Owners %q did not resolve to any users, cannot create clause.", owners)
+	}
+	if len(wildcards) == 0 {
+		return elastic.NewTermsQuery("userPermissions.user", clauseutils.StringsToInterfaces(terms)...), nil
+	}
+	if len(terms) == 0 && len(wildcards) == 1 {
+		return wildcards[0], nil
+	}
+
+	query := elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(wildcards...)
+	if len(terms) > 0 {
+		query.Should(elastic.NewTermsQuery("userPermissions.user", clauseutils.StringsToInterfaces(terms)...))
+	}
+	return query, nil
+}
+
+// wildcardOwnerQuery matches any of a set of owners, adding implicit zone wildcards where needed
+func wildcardOwnerQuery(owners []string) elastic.Query {
+	if len(owners) == 1 {
+		return elastic.NewWildcardQuery("userPermissions.user", clauseutils.AddImplicitUsernameWildcard(owners[0]))
+	}
+
+	query := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+	for _, owner := range owners {
+		query.Should(elastic.NewWildcardQuery("userPermissions.user", clauseutils.AddImplicitUsernameWildcard(owner)))
+	}
+	return query
+}
+
+func OwnerProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs OwnerArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	if realArgs.Owner == "" {
-		return nil, errors.New("No owner was passed, cannot create clause.")
+	if realArgs.Owner == "" && len(realArgs.Groups) == 0 {
+		return nil, errors.New("No owner or groups were passed, cannot create clause.")
 	}
 
-	processedOwner := clauseutils.AddImplicitUsernameWildcard(realArgs.Owner)
-	innerquery := elastic.NewBoolQuery().Must(elastic.NewTermQuery("userPermissions.permission", "own"), elastic.NewWildcardQuery("userPermissions.user", processedOwner))
+	var owners []string
+	if realArgs.Owner != "" {
+		owners = append(owners, realArgs.Owner)
+	}
+	members, err := clauseutils.ExpandGroups(ctx, realArgs.Groups)
+	if err != nil {
+		return nil, err
+	}
+	owners = append(owners, members...)
+	if len(owners) == 0 {
+		return nil, errors.New("The groups passed have no members, cannot create clause.")
+	}
+
+	var userquery elastic.Query
+	if _, ok := clauseutils.UserResolverFromContext(ctx); ok {
+		userquery, err = resolvedOwnerQuery(ctx, owners)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		userquery = wildcardOwnerQuery(owners)
+	}
+
+	innerquery := elastic.NewBoolQuery().Must(elastic.NewTermQuery("userPermissions.permission", "own"), userquery)
 	query := elastic.NewNestedQuery("userPermissions", innerquery)
 	return query, nil
 }
 
+func OwnerCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs OwnerArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	cost := clauseutils.AddCosts(clause.ClauseCost{Cost: clauseutils.NestedCost}, clauseutils.GroupMembersCost(ctx, realArgs.Groups))
+	if realArgs.Owner != "" {
+		cost.Cost += clauseutils.WildcardCost
+		cost.Terms++
+	}
+	return cost, nil
+}
+
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseType(typeKey, OwnerProcessor, documentation)
+	qd.SetClauseCoster(typeKey, OwnerCost)
 }
@@ -2,7 +2,11 @@ package owner
 
 import (
 	"context"
+	"reflect"
 	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
 )
 
 func TestOwnerProcessor(t *testing.T) {
@@ -115,3 +119,63 @@ func TestOwnerProcessor(t *testing.T) {
 		}
 	}
 }
+
+func TestOwnerProcessorResolved(t *testing.T) {
+	ctx := clauseutils.WithUserResolver(context.Background(), clauseutils.ZoneUserResolver{Zones: []string{"iplant", "cyverse"}})
+	args := map[string]interface{}{"owner": "mian"}
+
+	query, err := OwnerProcessor(ctx, args)
+	if err != nil {
+		t.Fatalf("OwnerProcessor failed with error: %q", err)
+	}
+
+	expected := elastic.NewNestedQuery("userPermissions", elastic.NewBoolQuery().Must(
+		elastic.NewTermQuery("userPermissions.permission", "own"),
+		elastic.NewTermsQuery("userPermissions.user", "mian#iplant", "mian#cyverse"),
+	))
+
+	source, err := query.Source()
+	if err != nil {
+		t.Errorf("Source get failed with error: %q", err)
+	}
+	expsource, err := expected.Source()
+	if err != nil {
+		t.Errorf("Source get on expected query failed with error: %q", err)
+	}
+	if !reflect.DeepEqual(source, expsource) {
+		t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+	}
+}
+
+func TestOwnerProcessorGroups(t *testing.T) {
+	resolver := clauseutils.GroupResolverFunc(func(_ context.Context, group string) ([]string, error) {
+		return []string{"ipctest"}, nil
+	})
+	ctx := clauseutils.WithGroupResolver(context.Background(), resolver)
+	args := map[string]interface{}{"owner": "mian", "groups": []string{"lab"}}
+
+	query, err := OwnerProcessor(ctx, args)
+	if err != nil {
+		t.Fatalf("OwnerProcessor failed with error: %q", err)
+	}
+
+	expected := elastic.NewNestedQuery("userPermissions", elastic.NewBoolQuery().Must(
+		elastic.NewTermQuery("userPermissions.permission", "own"),
+		elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+			elastic.NewWildcardQuery("userPermissions.user", "mian#*"),
+			elastic.NewWildcardQuery("userPermissions.user", "ipctest#*"),
+		),
+	))
+
+	source, err := query.Source()
+	if err != nil {
+		t.Errorf("Source get failed with error: %q", err)
+	}
+	expsource, err := expected.Source()
+	if err != nil {
+		t.Errorf("Source get on expected query failed with error: %q", err)
+	}
+	if !reflect.DeepEqual(source, expsource) {
+		t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/olivere/elastic/v7"
 )
@@ -19,28 +21,219 @@ var (
 	documentation = clause.ClauseDocumentation{
 		Summary: "Searches based on an object's full path",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"prefix": {Type: "string", Summary: "The path prefix to search for"},
+			"prefix":        {Type: "string", Summary: "The path prefix to search for"},
+			"prefixes":      {Type: "[]string", Summary: "Several path prefixes to search for, any of which may match. Combined with prefix, if both are passed."},
+			"exclude":       {Type: "[]string", Summary: "Folders to leave out of the search, along with everything inside them"},
+			"exact":         {Type: "bool", Summary: "Whether a prefix must be the object's entire path, rather than a prefix of it"},
+			"directory":     {Type: "bool", Summary: "Whether to treat the prefixes as folders, searching only within them: /iplant/home/foo then matches /iplant/home/foo/bar but not /iplant/home/foobar or the folder itself"},
+			"children_only": {Type: "bool", Summary: "Whether to search only the direct children of the folders given as prefixes, rather than searching them recursively"},
+			"min_depth":     {Type: "int", Summary: "The minimum depth below the folders given as prefixes, where their direct children are at depth 1"},
+			"max_depth":     {Type: "int", Summary: "The maximum depth below the folders given as prefixes, where their direct children are at depth 1. If 0 or unset, there is no maximum."},
+			"glob":          {Type: "string", Summary: "A glob the entire path must match, such as /iplant/home/*/analyses/**/*.bam, where * and ? do not match /, and ** matches any number of folders"},
+			"regex":         {Type: "string", Summary: "A regular expression the path must match. Use ^ and $ to match the entire path. May be combined with a prefix, which makes the search faster. Lookarounds, backreferences, lazy quantifiers, and patterns likely to be very slow are rejected."},
 		},
 	}
 )
 
 type PathArgs struct {
-	Prefix string
+	Prefix       string
+	Prefixes     []string
+	Exclude      []string
+	Exact        bool
+	Directory    bool
+	ChildrenOnly bool `mapstructure:"children_only"`
+	MinDepth     int  `mapstructure:"min_depth"`
+	MaxDepth     int  `mapstructure:"max_depth"`
+	Glob         string
+	Regex        string
 }
 
-func PathProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+// depthRange returns the range of depths below the prefix to search, with a maximum of 0 meaning unbounded, and whether any was requested
+func (a PathArgs) depthRange() (int, int, bool) {
+	if a.ChildrenOnly {
+		return 1, 1, true
+	}
+	if a.MinDepth == 0 && a.MaxDepth == 0 {
+		return 0, 0, false
+	}
+	min := a.MinDepth
+	if min < 1 {
+		min = 1
+	}
+	return min, a.MaxDepth, true
+}
+
+// allPrefixes returns the prefix and prefixes arguments together, without duplicates
+func (a PathArgs) allPrefixes() []string {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, prefix := range append([]string{a.Prefix}, a.Prefixes...) {
+		if prefix != "" && !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// folderPrefixes returns the prefixes with exactly one trailing separator each
+func (a PathArgs) folderPrefixes() []string {
+	prefixes := a.allPrefixes()
+	folders := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		folders[i] = strings.TrimRight(prefix, "/") + "/"
+	}
+	return folders
+}
+
+// excludedFolders returns the excluded folders without any trailing separator
+func (a PathArgs) excludedFolders() []string {
+	var folders []string
+	for _, exclude := range a.Exclude {
+		if folder := strings.TrimRight(exclude, "/"); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+func validateArgs(realArgs PathArgs) error {
+	prefixes := realArgs.allPrefixes()
+	if len(prefixes) == 0 && realArgs.Glob == "" && realArgs.Regex == "" && len(realArgs.excludedFolders()) == 0 {
+		return errors.New("No prefix, prefixes, glob, regex, or exclude was passed, cannot create clause.")
+	}
+	if len(realArgs.Exclude) != len(realArgs.excludedFolders()) {
+		return errors.New("Excluded folders cannot be empty or the root folder.")
+	}
+
+	_, _, hasDepth := realArgs.depthRange()
+	if (realArgs.Exact || realArgs.Directory || hasDepth) && len(prefixes) == 0 {
+		return errors.New("The exact, directory, children_only, min_depth, and max_depth arguments require a prefix.")
+	}
+	if realArgs.Exact && (realArgs.Directory || hasDepth) {
+		return errors.New("An exact path cannot be combined with directory, children_only, min_depth, or max_depth.")
+	}
+	if realArgs.ChildrenOnly && (realArgs.MinDepth != 0 || realArgs.MaxDepth != 0) {
+		return errors.New("children_only cannot be combined with min_depth or max_depth.")
+	}
+	if realArgs.MinDepth < 0 || realArgs.MaxDepth < 0 {
+		return errors.New("Depths cannot be negative.")
+	}
+	if realArgs.MaxDepth != 0 && realArgs.MinDepth > realArgs.MaxDepth {
+		return fmt.Errorf("The min_depth %d is greater than the max_depth %d.", realArgs.MinDepth, realArgs.MaxDepth)
+	}
+	return nil
+}
+
+// depthRegexp builds a Lucene regexp matching paths between min and max levels below any of folders, with a max of 0 meaning unbounded
+func depthRegexp(folders []string, min, max int) string {
+	escaped := make([]string, len(folders))
+	for i, folder := range folders {
+		escaped[i] = clauseutils.EscapeRegexp(folder)
+	}
+	pattern := escaped[0]
+	if len(escaped) > 1 {
+		pattern = "(" + strings.Join(escaped, "|") + ")"
+	}
+	pattern += "[^/]+"
+	switch {
+	case max == 0:
+		pattern += fmt.Sprintf("(/[^/]+){%d,}", min-1)
+	case max > 1:
+		pattern += fmt.Sprintf("(/[^/]+){%d,%d}", min-1, max-1)
+	}
+	return pattern
+}
+
+// anyPrefixQuery creates a query matching paths starting with any of prefixes
+func anyPrefixQuery(prefixes []string) elastic.Query {
+	if len(prefixes) == 1 {
+		return elastic.NewPrefixQuery("path", prefixes[0])
+	}
+	query := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+	for _, prefix := range prefixes {
+		query.Should(elastic.NewPrefixQuery("path", prefix))
+	}
+	return query
+}
+
+// excludeQueries creates the queries matching the excluded folders and their contents
+func excludeQueries(folders []string) []elastic.Query {
+	if len(folders) == 0 {
+		return nil
+	}
+	queries := []elastic.Query{elastic.NewTermsQuery("path", clauseutils.StringsToInterfaces(folders)...)}
+	for _, folder := range folders {
+		queries = append(queries, elastic.NewPrefixQuery("path", folder+"/"))
+	}
+	return queries
+}
+
+// quoteList formats a list of paths for a summary, bracketing it if there is more than one
+func quoteList(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = fmt.Sprintf("\"%s\"", path)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+func PathProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs PathArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	if realArgs.Prefix == "" {
-		return nil, errors.New("No prefix was passed, cannot create clause.")
+	if err = validateArgs(realArgs); err != nil {
+		return nil, err
 	}
 
-	query := elastic.NewPrefixQuery("path", realArgs.Prefix)
-	return query, nil
+	var queries []elastic.Query
+	prefixes := realArgs.allPrefixes()
+	min, max, hasDepth := realArgs.depthRange()
+	switch {
+	case realArgs.Exact && len(prefixes) == 1:
+		queries = append(queries, elastic.NewTermQuery("path", prefixes[0]))
+	case realArgs.Exact:
+		queries = append(queries, elastic.NewTermsQuery("path", clauseutils.StringsToInterfaces(prefixes)...))
+	case realArgs.Directory || hasDepth:
+		queries = append(queries, anyPrefixQuery(realArgs.folderPrefixes()))
+		if hasDepth {
+			queries = append(queries, clauseutils.LuceneRegexpQuery(ctx, "path", depthRegexp(realArgs.folderPrefixes(), min, max)))
+		}
+	case len(prefixes) > 0:
+		queries = append(queries, anyPrefixQuery(prefixes))
+	}
+
+	if realArgs.Glob != "" {
+		pattern, err := clauseutils.GlobToRegexp(realArgs.Glob)
+		if err != nil {
+			return nil, err
+		}
+		// the glob's literal prefix lets Elasticsearch skip most paths before running the regexp
+		if literal := clauseutils.GlobLiteralPrefix(realArgs.Glob); literal != "" && len(prefixes) == 0 {
+			queries = append(queries, elastic.NewPrefixQuery("path", literal))
+		}
+		queries = append(queries, clauseutils.LuceneRegexpQuery(ctx, "path", pattern))
+	}
+
+	if realArgs.Regex != "" {
+		regexQuery, err := clauseutils.RegexpQuery(ctx, "path", realArgs.Regex)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, regexQuery)
+	}
+
+	excludes := excludeQueries(realArgs.excludedFolders())
+	if len(queries) == 1 && len(excludes) == 0 {
+		return queries[0], nil
+	}
+	return elastic.NewBoolQuery().Must(queries...).MustNot(excludes...), nil
 }
 
 func PathSummary(_ context.Context, args map[string]interface{}) (string, error) {
@@ -50,13 +243,89 @@ func PathSummary(_ context.Context, args map[string]interface{}) (string, error)
 		return "", err
 	}
 
-	if realArgs.Prefix == "" {
-		return "", errors.New("No prefix was passed, cannot create clause.")
+	if err = validateArgs(realArgs); err != nil {
+		return "", err
 	}
 
-	return fmt.Sprintf("path=\"%s\"", realArgs.Prefix), nil
+	var parts []string
+	prefixes := realArgs.allPrefixes()
+	min, max, hasDepth := realArgs.depthRange()
+	switch {
+	case realArgs.Exact:
+		parts = append(parts, fmt.Sprintf("path==%s", quoteList(prefixes)))
+	case realArgs.ChildrenOnly:
+		parts = append(parts, fmt.Sprintf("path=%s(children)", quoteList(realArgs.folderPrefixes())))
+	case hasDepth && max == 0:
+		parts = append(parts, fmt.Sprintf("path=%s(depth %d+)", quoteList(realArgs.folderPrefixes()), min))
+	case hasDepth:
+		parts = append(parts, fmt.Sprintf("path=%s(depth %d-%d)", quoteList(realArgs.folderPrefixes()), min, max))
+	case realArgs.Directory:
+		parts = append(parts, fmt.Sprintf("path=%s", quoteList(realArgs.folderPrefixes())))
+	case len(prefixes) > 0:
+		parts = append(parts, fmt.Sprintf("path=%s", quoteList(prefixes)))
+	}
+	if realArgs.Glob != "" {
+		parts = append(parts, fmt.Sprintf("path~\"%s\"", realArgs.Glob))
+	}
+	if realArgs.Regex != "" {
+		parts = append(parts, fmt.Sprintf("path=~/%s/", realArgs.Regex))
+	}
+	if excluded := realArgs.excludedFolders(); len(excluded) > 0 {
+		parts = append(parts, fmt.Sprintf("path!=%s", quoteList(excluded)))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func PathCost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs PathArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	cost := clause.ClauseCost{}
+	prefixes := realArgs.allPrefixes()
+	count := len(prefixes)
+	_, _, hasDepth := realArgs.depthRange()
+	switch {
+	case realArgs.Exact:
+		cost = clauseutils.AddCosts(cost, clause.ClauseCost{Cost: clauseutils.TermCost * float64(count), Terms: count})
+	case hasDepth:
+		cost = clauseutils.AddCosts(cost, clause.ClauseCost{Cost: clauseutils.PrefixCost*float64(count) + clauseutils.WildcardCost, Terms: count + 1})
+	case count > 0:
+		cost = clauseutils.AddCosts(cost, clause.ClauseCost{Cost: clauseutils.PrefixCost * float64(count), Terms: count})
+	}
+
+	if excluded := len(realArgs.excludedFolders()); excluded > 0 {
+		cost = clauseutils.AddCosts(cost, clause.ClauseCost{Cost: (clauseutils.TermCost + clauseutils.PrefixCost) * float64(excluded), Terms: 2 * excluded})
+	}
+
+	// a prefix keeps Elasticsearch from running globs and regexes against every path
+	anchored := count > 0
+	if realArgs.Glob != "" {
+		globCost := clause.ClauseCost{Cost: clauseutils.WildcardCost, Terms: 1}
+		if literal := clauseutils.GlobLiteralPrefix(realArgs.Glob); literal != "" {
+			anchored = true
+			if count == 0 {
+				globCost = clauseutils.AddCosts(globCost, clause.ClauseCost{Cost: clauseutils.PrefixCost, Terms: 1})
+			}
+		} else if !anchored {
+			globCost = clause.ClauseCost{Cost: clauseutils.LeadingWildcardCost, Terms: 1, LeadingWildcards: 1}
+		}
+		cost = clauseutils.AddCosts(cost, globCost)
+	}
+	if realArgs.Regex != "" {
+		regexCost := clauseutils.RegexpCost(realArgs.Regex)
+		if anchored {
+			regexCost.LeadingWildcards = 0
+			regexCost.Cost = clauseutils.WildcardCost
+		}
+		cost = clauseutils.AddCosts(cost, regexCost)
+	}
+	return cost, nil
 }
 
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseTypeSummarized(typeKey, PathProcessor, documentation, PathSummary)
+	qd.SetClauseCoster(typeKey, PathCost)
 }
@@ -3,7 +3,10 @@ package path
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/olivere/elastic/v7"
 )
 
 func TestPathProcessor(t *testing.T) {
@@ -51,3 +54,195 @@ func TestPathProcessor(t *testing.T) {
 		})
 	}
 }
+
+func TestPathRegex(t *testing.T) {
+	cases := []struct {
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			args:     map[string]interface{}{"regex": `^/iplant/home/[^/]+/analyses/.*\.bam$`},
+			expected: elastic.NewRegexpQuery("path", `/iplant/home/[^/]+/analyses/.*\.bam`).MaxDeterminizedStates(10000),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo/", "regex": `^/iplant/home/foo/.*\.bam$`},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewPrefixQuery("path", "/iplant/home/foo/"),
+				elastic.NewRegexpQuery("path", `/iplant/home/foo/.*\.bam`).MaxDeterminizedStates(10000),
+			),
+		},
+		{args: map[string]interface{}{"regex": "(.*)*"}, shouldErr: true},     // catastrophic
+		{args: map[string]interface{}{"regex": "a{1,5000}"}, shouldErr: true}, // huge repetition
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.args), func(t *testing.T) {
+			query, err := PathProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("PathProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("PathProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestPathOptions(t *testing.T) {
+	cases := []struct {
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			args:     map[string]interface{}{"prefix": "/iplant/home/foo", "exact": true},
+			expected: elastic.NewTermQuery("path", "/iplant/home/foo"),
+		},
+		{
+			args:     map[string]interface{}{"prefix": "/iplant/home/foo", "directory": true},
+			expected: elastic.NewPrefixQuery("path", "/iplant/home/foo/"),
+		},
+		{
+			args:     map[string]interface{}{"prefix": "/iplant/home/foo/", "directory": true},
+			expected: elastic.NewPrefixQuery("path", "/iplant/home/foo/"),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo.bar", "children_only": true},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewPrefixQuery("path", "/iplant/home/foo.bar/"),
+				elastic.NewRegexpQuery("path", `/iplant/home/foo\.bar/[^/]+`).MaxDeterminizedStates(10000),
+			),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo", "min_depth": float64(2), "max_depth": float64(3)},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewPrefixQuery("path", "/iplant/home/foo/"),
+				elastic.NewRegexpQuery("path", `/iplant/home/foo/[^/]+(/[^/]+){1,2}`).MaxDeterminizedStates(10000),
+			),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo", "min_depth": 2},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewPrefixQuery("path", "/iplant/home/foo/"),
+				elastic.NewRegexpQuery("path", `/iplant/home/foo/[^/]+(/[^/]+){1,}`).MaxDeterminizedStates(10000),
+			),
+		},
+		{
+			args: map[string]interface{}{"glob": "/iplant/home/*/analyses/**/*.bam"},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewPrefixQuery("path", "/iplant/home/"),
+				elastic.NewRegexpQuery("path", `/iplant/home/[^/]*/analyses/(.*/)?[^/]*\.bam`).MaxDeterminizedStates(10000),
+			),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo", "prefixes": []interface{}{"/iplant/home/shared/lab", "/iplant/home/foo"}},
+			expected: elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+				elastic.NewPrefixQuery("path", "/iplant/home/foo"),
+				elastic.NewPrefixQuery("path", "/iplant/home/shared/lab"),
+			),
+		},
+		{
+			args:     map[string]interface{}{"prefixes": []string{"/a", "/b"}, "exact": true},
+			expected: elastic.NewTermsQuery("path", "/a", "/b"),
+		},
+		{
+			args: map[string]interface{}{"prefixes": []string{"/a", "/b/"}, "children_only": true},
+			expected: elastic.NewBoolQuery().Must(
+				elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(elastic.NewPrefixQuery("path", "/a/"), elastic.NewPrefixQuery("path", "/b/")),
+				elastic.NewRegexpQuery("path", `(/a/|/b/)[^/]+`).MaxDeterminizedStates(10000),
+			),
+		},
+		{
+			args: map[string]interface{}{"prefix": "/iplant/home/foo", "exclude": []string{"/iplant/home/foo/trash/", "/iplant/home/foo/tmp"}},
+			expected: elastic.NewBoolQuery().Must(elastic.NewPrefixQuery("path", "/iplant/home/foo")).MustNot(
+				elastic.NewTermsQuery("path", "/iplant/home/foo/trash", "/iplant/home/foo/tmp"),
+				elastic.NewPrefixQuery("path", "/iplant/home/foo/trash/"),
+				elastic.NewPrefixQuery("path", "/iplant/home/foo/tmp/"),
+			),
+		},
+		{
+			args: map[string]interface{}{"exclude": []string{"/a"}},
+			expected: elastic.NewBoolQuery().MustNot(
+				elastic.NewTermsQuery("path", "/a"),
+				elastic.NewPrefixQuery("path", "/a/"),
+			),
+		},
+		{args: map[string]interface{}{"prefix": "/a", "exclude": []string{"/"}}, shouldErr: true},              // excluding the root
+		{args: map[string]interface{}{"prefixes": "/a"}, shouldErr: true},                                      // bad type
+		{args: map[string]interface{}{"exact": true}, shouldErr: true},                                         // no prefix
+		{args: map[string]interface{}{"prefix": "/a", "exact": true, "directory": true}, shouldErr: true},      // exact and directory
+		{args: map[string]interface{}{"prefix": "/a", "children_only": true, "max_depth": 2}, shouldErr: true}, // children_only and depth
+		{args: map[string]interface{}{"prefix": "/a", "min_depth": 3, "max_depth": 2}, shouldErr: true},        // inverted depths
+		{args: map[string]interface{}{"prefix": "/a", "max_depth": -1}, shouldErr: true},                       // negative depth
+		{args: map[string]interface{}{"glob": "/a/[b"}, shouldErr: true},                                       // bad glob
+		{args: map[string]interface{}{"prefix": "/a", "min_depth": "deep"}, shouldErr: true},                   // bad type
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.args), func(t *testing.T) {
+			query, err := PathProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("PathProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("PathProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestPathSummary(t *testing.T) {
+	cases := []struct {
+		args     map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"prefix": "/a/b"}, `path="/a/b"`},
+		{map[string]interface{}{"prefix": "/a/b", "exact": true}, `path=="/a/b"`},
+		{map[string]interface{}{"prefix": "/a/b", "directory": true}, `path="/a/b/"`},
+		{map[string]interface{}{"prefix": "/a/b", "children_only": true}, `path="/a/b/"(children)`},
+		{map[string]interface{}{"prefix": "/a/b", "min_depth": 2, "max_depth": 4}, `path="/a/b/"(depth 2-4)`},
+		{map[string]interface{}{"prefix": "/a/b", "max_depth": 2}, `path="/a/b/"(depth 1-2)`},
+		{map[string]interface{}{"prefix": "/a/b", "min_depth": 2}, `path="/a/b/"(depth 2+)`},
+		{map[string]interface{}{"glob": "/a/*.bam"}, `path~"/a/*.bam"`},
+		{map[string]interface{}{"prefix": "/a", "prefixes": []string{"/b", "/c"}}, `path=["/a","/b","/c"]`},
+		{map[string]interface{}{"prefixes": []string{"/a", "/b"}, "children_only": true}, `path=["/a/","/b/"](children)`},
+		{map[string]interface{}{"prefix": "/a", "exclude": []string{"/a/trash/"}}, `path="/a" path!="/a/trash"`},
+		{map[string]interface{}{"prefix": "/a", "exclude": []string{"/a/b", "/a/c"}}, `path="/a" path!=["/a/b","/a/c"]`},
+		{map[string]interface{}{"prefix": "/a/", "regex": "/a/.*"}, `path="/a/" path=~//a/.*/`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := PathSummary(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("PathSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
@@ -20,53 +20,77 @@ var (
 	documentation = clause.ClauseDocumentation{
 		Summary: "Searches based on an object's permissions for specified users",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"users":              {Type: "[]string", Summary: "The users to search for. If a given username is not qualified (does not contain a # character), a wildcard will be added unless 'exact' is set to true."},
+			"users":              {Type: "[]string", Summary: "The users to search for. If a given username is not qualified (does not contain a # character), it will be qualified by the configured user resolver, which by default adds a wildcard, unless 'exact' is set to true."},
+			"groups":             {Type: "[]string", Summary: "Groups whose members should also be searched for. Groups are expanded to their members' usernames using the configured group resolver, and those usernames are handled as in 'users'."},
 			"permission":         {Type: "string", Summary: "The permission to check for; should be one of 'own', 'write', or 'read', with own implying write implying read. To search for objects where the user has no permissions at all, use 'read' in a negation and set permission_recurse to true."},
 			"permission_recurse": {Type: "bool", Summary: "If set to true, 'read' permission will also match write and own, and 'write' permission will also match own."},
-			"exact":              {Type: "bool", Summary: "If set to true, do not add implicit wildcards even to usernames without the # character. This will in general effectively ignore those arguments, but may improve performance slightly if all the usernames are already known to be qualified appropriately."},
+			"exact":              {Type: "bool", Summary: "If set to true, do not resolve or add implicit wildcards even to usernames without the # character. This will in general effectively ignore those arguments, but may improve performance slightly if all the usernames are already known to be qualified appropriately."},
 		},
 	}
 )
 
 type PermissionsArgs struct {
 	Users             []string
+	Groups            []string
 	Permission        string
 	PermissionRecurse bool `mapstructure:"permission_recurse"`
 	Exact             bool
 }
 
-func PermissionsProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+func PermissionsProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs PermissionsArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(realArgs.Users) == 0 {
-		return nil, errors.New("No users were passed, cannot create clause.")
+	return PermissionsQuery(ctx, realArgs)
+}
+
+// PermissionsQuery builds the nested userPermissions query described by a set of already-decoded PermissionsArgs.
+// It is exported so other clauses can build on permissions searches.
+func PermissionsQuery(ctx context.Context, realArgs PermissionsArgs) (elastic.Query, error) {
+	if len(realArgs.Users) == 0 && len(realArgs.Groups) == 0 {
+		return nil, errors.New("No users or groups were passed, cannot create clause.")
 	}
 
 	if realArgs.Permission == "" {
 		return nil, errors.New("No permission was passed, cannot create clause.")
 	}
 
-	if realArgs.Permission != "own" && realArgs.Permission != "write" && realArgs.Permission != "read" {
+	if !ValidPermission(realArgs.Permission) {
 		return nil, fmt.Errorf("Got a permission of %q, but expected read, write, or own.", realArgs.Permission)
 	}
 
+	members, err := clauseutils.ExpandGroups(ctx, realArgs.Groups)
+	if err != nil {
+		return nil, err
+	}
+	users := append(append([]string{}, realArgs.Users...), members...)
+
 	var innerquery *elastic.BoolQuery
 	var terms []interface{}
 	var shoulds []elastic.Query
 
-	for _, user := range realArgs.Users {
-		processedUser := clauseutils.AddImplicitUsernameWildcard(user)
-		if processedUser == user || realArgs.Exact {
+	for _, user := range users {
+		if realArgs.Exact {
 			terms = append(terms, user)
-		} else {
-			shoulds = append(shoulds, elastic.NewWildcardQuery("userPermissions.user", processedUser))
+			continue
+		}
+		resolved, err := clauseutils.ResolveUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, clauseutils.StringsToInterfaces(resolved.Terms)...)
+		if resolved.Wildcard != "" {
+			shoulds = append(shoulds, elastic.NewWildcardQuery("userPermissions.user", resolved.Wildcard))
 		}
 	}
 
+	if len(terms) == 0 && len(shoulds) == 0 {
+		return nil, errors.New("The users passed did not resolve to any qualified usernames, cannot create clause.")
+	}
+
 	if realArgs.PermissionRecurse && realArgs.Permission == "read" {
 		// We don't need to filter on the permission at all; any permission matches.
 		innerquery = elastic.NewBoolQuery()
@@ -97,6 +121,30 @@ func PermissionsProcessor(_ context.Context, args map[string]interface{}) (elast
 	return query, nil
 }
 
+// ValidPermission checks whether a permission is one of 'own', 'write', or 'read'
+func ValidPermission(permission string) bool {
+	return permission == "own" || permission == "write" || permission == "read"
+}
+
+func PermissionsCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs PermissionsArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	cost := clauseutils.AddCosts(clause.ClauseCost{Cost: clauseutils.NestedCost, Terms: len(realArgs.Users)}, clauseutils.GroupMembersCost(ctx, realArgs.Groups))
+	for _, user := range realArgs.Users {
+		if realArgs.Exact || clauseutils.IsQualifiedUsername(user, "") {
+			cost.Cost += clauseutils.TermCost
+		} else {
+			cost.Cost += clauseutils.WildcardCost
+		}
+	}
+	return cost, nil
+}
+
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseType(typeKey, PermissionsProcessor, documentation)
+	qd.SetClauseCoster(typeKey, PermissionsCost)
 }
@@ -3,7 +3,11 @@ package permissions
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
 )
 
 type permissionTestCase struct {
@@ -287,3 +291,105 @@ func TestPermissionsProcessor(t *testing.T) {
 		})
 	}
 }
+
+func TestPermissionsProcessorResolved(t *testing.T) {
+	ctx := clauseutils.WithUserResolver(context.Background(), clauseutils.ZoneUserResolver{Zones: []string{"iplant"}})
+	args := map[string]interface{}{
+		"users":      []string{"mian", "ipctest#foo"},
+		"permission": "own",
+	}
+
+	query, err := PermissionsProcessor(ctx, args)
+	if err != nil {
+		t.Fatalf("PermissionsProcessor failed with error: %q", err)
+	}
+
+	expected := elastic.NewNestedQuery("userPermissions", elastic.NewBoolQuery().Must(
+		elastic.NewTermQuery("userPermissions.permission", "own"),
+		elastic.NewTermsQuery("userPermissions.user", "mian#iplant", "ipctest#foo"),
+	))
+
+	source, err := query.Source()
+	if err != nil {
+		t.Errorf("Source get failed with error: %q", err)
+	}
+	expsource, err := expected.Source()
+	if err != nil {
+		t.Errorf("Source get on expected query failed with error: %q", err)
+	}
+	if !reflect.DeepEqual(source, expsource) {
+		t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+	}
+}
+
+func TestPermissionsProcessorGroups(t *testing.T) {
+	resolver := clauseutils.GroupResolverFunc(func(_ context.Context, group string) ([]string, error) {
+		return []string{"mian#iplant", "ipctest#iplant"}, nil
+	})
+
+	cases := []struct {
+		name      string
+		ctx       context.Context
+		shouldErr bool
+	}{
+		{name: "resolved", ctx: clauseutils.WithGroupResolver(context.Background(), resolver)},
+		{name: "no-resolver", ctx: context.Background(), shouldErr: true},
+		{name: "too-many", ctx: clauseutils.WithMaxGroupMembers(clauseutils.WithGroupResolver(context.Background(), resolver), 1), shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args := map[string]interface{}{
+				"groups":     []string{"lab"},
+				"permission": "read",
+			}
+
+			query, err := PermissionsProcessor(c.ctx, args)
+			if c.shouldErr && err == nil {
+				t.Errorf("PermissionsProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("PermissionsProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				expected := elastic.NewNestedQuery("userPermissions", elastic.NewBoolQuery().Must(
+					elastic.NewTermQuery("userPermissions.permission", "read"),
+					elastic.NewTermsQuery("userPermissions.user", "mian#iplant", "ipctest#iplant"),
+				))
+
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestPermissionsCostGroups(t *testing.T) {
+	ctx := clauseutils.WithMaxGroupMembers(context.Background(), 50)
+	cost, err := PermissionsCost(ctx, map[string]interface{}{"users": []string{"mian#iplant"}, "groups": []string{"lab"}})
+	if err != nil {
+		t.Fatalf("PermissionsCost failed with error: %q", err)
+	}
+	// without a resolver, the group may expand to as many users as the member limit
+	if cost.Terms != 51 {
+		t.Errorf("Got %d terms, expected the user plus up to 50 group members", cost.Terms)
+	}
+
+	ctx = clauseutils.WithGroupResolver(ctx, clauseutils.GroupResolverFunc(func(_ context.Context, group string) ([]string, error) {
+		return []string{"a#iplant", "b#iplant", "c#iplant"}, nil
+	}))
+	cost, err = PermissionsCost(ctx, map[string]interface{}{"users": []string{"mian#iplant"}, "groups": []string{"lab"}})
+	if err != nil {
+		t.Fatalf("PermissionsCost failed with error: %q", err)
+	}
+	if cost.Terms != 4 {
+		t.Errorf("Got %d terms, expected the user plus the 3 group members", cost.Terms)
+	}
+}
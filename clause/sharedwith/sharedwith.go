@@ -0,0 +1,105 @@
+package sharedwith
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clause/permissions"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	typeKey = "shared_with"
+)
+
+var (
+	documentation = clause.ClauseDocumentation{
+		Summary: "Searches for objects shared with a user: objects the user has at least the given permission on, but does not own",
+		Args: map[string]clause.ClauseArgumentDocumentation{
+			"user":       {Type: "string", Summary: "The user objects are shared with. Unqualified usernames are handled as in the 'permissions' clause. If blank, the current user is used."},
+			"permission": {Type: "string", Summary: "The minimum permission the user must have; one of 'read' or 'write'. Defaults to 'read'."},
+		},
+	}
+)
+
+type SharedWithArgs struct {
+	User       string
+	Permission string
+}
+
+// permission returns the minimum permission the user must have, defaulting to read
+func (a SharedWithArgs) permission() (string, error) {
+	switch a.Permission {
+	case "":
+		return "read", nil
+	case "read", "write":
+		return a.Permission, nil
+	}
+	return "", fmt.Errorf("Got a permission of %q, but expected read or write.", a.Permission)
+}
+
+func SharedWithProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	var realArgs SharedWithArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := clauseutils.UserOrCurrent(ctx, realArgs.User)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := realArgs.permission()
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := permissions.PermissionsQuery(ctx, permissions.PermissionsArgs{Users: []string{user}, Permission: permission, PermissionRecurse: true})
+	if err != nil {
+		return nil, err
+	}
+	owned, err := permissions.PermissionsQuery(ctx, permissions.PermissionsArgs{Users: []string{user}, Permission: "own"})
+	if err != nil {
+		return nil, err
+	}
+
+	query := elastic.NewBoolQuery().Must(granted).MustNot(owned)
+	return query, nil
+}
+
+func SharedWithSummary(ctx context.Context, args map[string]interface{}) (string, error) {
+	var realArgs SharedWithArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	permission, err := realArgs.permission()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := clauseutils.UserOrCurrent(ctx, realArgs.User)
+	if err != nil {
+		user = "<current user>"
+	}
+
+	if permission == "read" {
+		return fmt.Sprintf("shared_with=\"%s\"", user), nil
+	}
+	return fmt.Sprintf("shared_with=\"%s\"(%s)", user, permission), nil
+}
+
+func SharedWithCost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	return clause.ClauseCost{Cost: 2 * (clauseutils.NestedCost + clauseutils.WildcardCost), Terms: 1}, nil
+}
+
+func Register(qd *querydsl.QueryDSL) {
+	qd.AddClauseTypeSummarized(typeKey, SharedWithProcessor, documentation, SharedWithSummary)
+	qd.SetClauseCoster(typeKey, SharedWithCost)
+}
@@ -0,0 +1,108 @@
+package sharedwith
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+func TestSharedWithProcessor(t *testing.T) {
+	cases := []struct {
+		user          interface{}
+		permission    string
+		currentUser   string
+		expectedUser  string
+		expectedPerms []interface{}
+		shouldErr     bool
+	}{
+		{user: "mian#iplant", expectedUser: "mian#iplant"},
+		{user: "mian#iplant", permission: "write", expectedUser: "mian#iplant", expectedPerms: []interface{}{"write", "own"}},
+		{currentUser: "ipctest#iplant", expectedUser: "ipctest#iplant"},
+		{user: "mian#iplant", permission: "own", shouldErr: true}, // can't be shared with ownership without owning
+		{shouldErr: true},            // no user and no current user
+		{user: 444, shouldErr: true}, // bad type
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%T(%+v)-%s-current:%s", c.user, c.user, c.permission, c.currentUser), func(t *testing.T) {
+			ctx := context.Background()
+			if c.currentUser != "" {
+				ctx = clauseutils.WithCurrentUser(ctx, c.currentUser)
+			}
+			args := map[string]interface{}{"user": c.user, "permission": c.permission}
+
+			query, err := SharedWithProcessor(ctx, args)
+			if c.shouldErr && err == nil {
+				t.Errorf("SharedWithProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("SharedWithProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				grantedInner := elastic.NewBoolQuery()
+				if c.expectedPerms != nil {
+					grantedInner.Must(elastic.NewTermsQuery("userPermissions.permission", c.expectedPerms...))
+				}
+				grantedInner.Must(elastic.NewTermsQuery("userPermissions.user", c.expectedUser))
+				ownedInner := elastic.NewBoolQuery().Must(
+					elastic.NewTermQuery("userPermissions.permission", "own"),
+					elastic.NewTermsQuery("userPermissions.user", c.expectedUser),
+				)
+				expected := elastic.NewBoolQuery().
+					Must(elastic.NewNestedQuery("userPermissions", grantedInner)).
+					MustNot(elastic.NewNestedQuery("userPermissions", ownedInner))
+
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedWithSummary(t *testing.T) {
+	cases := []struct {
+		args        map[string]interface{}
+		currentUser string
+		expected    string
+		shouldErr   bool
+	}{
+		{map[string]interface{}{"user": "mian"}, "", "shared_with=\"mian\"", false},
+		{map[string]interface{}{"user": "mian", "permission": "write"}, "", "shared_with=\"mian\"(write)", false},
+		{map[string]interface{}{}, "ipctest", "shared_with=\"ipctest\"", false},
+		{map[string]interface{}{}, "", "shared_with=\"<current user>\"", false},
+		{map[string]interface{}{"user": "mian", "permission": "own"}, "", "own", true}, // rejected, as by the processor
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			ctx := context.Background()
+			if c.currentUser != "" {
+				ctx = clauseutils.WithCurrentUser(ctx, c.currentUser)
+			}
+			summary, err := SharedWithSummary(ctx, c.args)
+			if c.shouldErr {
+				if err == nil {
+					t.Errorf("SharedWithSummary did not fail, returned %q", summary)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("SharedWithSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package size
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
@@ -20,20 +19,49 @@ var (
 	documentation = clause.ClauseDocumentation{
 		Summary: "Searches based on an object's file size. Searches matching this clause will only include files, as folders do not store a size.",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"from": {Type: "string", Summary: "The lower end of the range (inclusive). Pass as a string, either a number of bytes or a number followed by optional whitespace and then one of 'KB', 'MB', 'GB', or 'TB', which refer to powers of 1024 bytes (commonly called kilo/mebi/gibi/tebibytes)."},
-			"to":   {Type: "string", Summary: "The upper end of the range (inclusive). Pass as a string, as with 'from'."},
+			"from":           {Type: "string", Summary: "The lower end of the range (inclusive, unless from_exclusive is set). Pass as a string, either a number of bytes or a number with any number of decimal places followed by optional whitespace and then a unit: 'B' or 'bytes'; 'KiB', 'MiB', 'GiB', 'TiB', 'PiB', or 'EiB', which refer to powers of 1024 bytes; or 'KB', 'MB', 'GB', 'TB', 'PB', or 'EB', which also refer to powers of 1024 bytes, for compatibility, unless the deployment is configured to use powers of 1000. Units are case-insensitive."},
+			"to":             {Type: "string", Summary: "The upper end of the range (inclusive, unless to_exclusive is set). Pass as a string, as with 'from'."},
+			"size":           {Type: "string", Summary: clauseutils.RangeExpressionDocumentation + " Sizes are in any format 'from' accepts, such as '>= 1GB' or '500 KB..2 MB'."},
+			"from_exclusive": {Type: "bool", Summary: "Whether to leave the lower end itself out of the range"},
+			"to_exclusive":   {Type: "bool", Summary: "Whether to leave the upper end itself out of the range"},
 		},
+		Kinds: []clause.DocumentKind{clause.File},
 	}
 )
 
 type SizeArgs struct {
-	From string
-	To   string
+	From          string
+	To            string
+	FromExclusive bool `mapstructure:"from_exclusive"`
+	ToExclusive   bool `mapstructure:"to_exclusive"`
 }
 
-func SizeProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+// decodeArgs decodes the clause's args, filling in from, to, and their exclusivity from a range in shorthand passed as
+// the size argument, which is also returned
+func decodeArgs(args map[string]interface{}) (SizeArgs, *clauseutils.RangeExpression, error) {
 	var realArgs SizeArgs
-	err := mapstructure.Decode(args, &realArgs)
+	expr, args, err := clauseutils.RangeExpressionArg(args, typeKey)
+	if err != nil {
+		return realArgs, nil, err
+	}
+
+	err = mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return realArgs, nil, err
+	}
+
+	if expr != nil {
+		if realArgs.From != "" || realArgs.To != "" || realArgs.FromExclusive || realArgs.ToExclusive {
+			return realArgs, nil, errors.New("The size argument cannot be combined with from, to, from_exclusive, or to_exclusive.")
+		}
+		realArgs.From, realArgs.To = expr.From, expr.To
+		realArgs.FromExclusive, realArgs.ToExclusive = expr.FromExclusive, expr.ToExclusive
+	}
+	return realArgs, expr, nil
+}
+
+func SizeProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+	realArgs, _, err := decodeArgs(args)
 	if err != nil {
 		return nil, err
 	}
@@ -42,35 +70,42 @@ func SizeProcessor(_ context.Context, args map[string]interface{}) (elastic.Quer
 		return nil, errors.New("Neither from nor to was passed, cannot create clause.")
 	}
 
-	var from, to int64
-	var rangetype clauseutils.RangeType
+	rb := clauseutils.NewRangeBuilder("fileSize")
 
 	if realArgs.From != "" {
-		rangetype = clauseutils.LowerOnly
-		from, err = clauseutils.StringToFilesize(realArgs.From)
+		from, err := clauseutils.FilesizeFromContext(ctx, realArgs.From)
 		if err != nil {
 			return nil, err
 		}
+		rb.From(from, realArgs.FromExclusive)
 	}
 
 	if realArgs.To != "" {
-		if rangetype == clauseutils.LowerOnly {
-			rangetype = clauseutils.Both
-		} else {
-			rangetype = clauseutils.UpperOnly
-		}
-		to, err = clauseutils.StringToFilesize(realArgs.To)
+		to, err := clauseutils.FilesizeFromContext(ctx, realArgs.To)
 		if err != nil {
 			return nil, err
 		}
+		rb.To(to, realArgs.ToExclusive)
 	}
 
-	return clauseutils.CreateRangeQuery("fileSize", rangetype, from, to), nil
+	return rb.Build()
 }
 
-func SizeSummary(_ context.Context, args map[string]interface{}) (string, error) {
-	var realArgs SizeArgs
-	err := mapstructure.Decode(args, &realArgs)
+// formatSize formats a size argument for a summary, leaving it as it was if it cannot be parsed
+func formatSize(ctx context.Context, size string) string {
+	if size == "" {
+		return ""
+	}
+	bytes, err := clauseutils.FilesizeFromContext(ctx, size)
+	if err != nil {
+		return size
+	}
+	system, _ := clauseutils.SizeUnitSystemFromContext(ctx)
+	return clauseutils.FormatFilesize(bytes, system)
+}
+
+func SizeSummary(ctx context.Context, args map[string]interface{}) (string, error) {
+	realArgs, expr, err := decodeArgs(args)
 	if err != nil {
 		return "", err
 	}
@@ -79,9 +114,19 @@ func SizeSummary(_ context.Context, args map[string]interface{}) (string, error)
 		return "", errors.New("Neither from nor to was passed, cannot create clause.")
 	}
 
-	return fmt.Sprintf("size=%s--%s", realArgs.From, realArgs.To), nil
+	if expr != nil {
+		formatted := *expr
+		formatted.From, formatted.To = formatSize(ctx, expr.From), formatSize(ctx, expr.To)
+		return formatted.Summary(typeKey), nil
+	}
+	return clauseutils.RangeSummary(typeKey, formatSize(ctx, realArgs.From), formatSize(ctx, realArgs.To), realArgs.FromExclusive, realArgs.ToExclusive), nil
+}
+
+func SizeCost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	return clause.ClauseCost{Cost: clauseutils.RangeCost, Terms: 1}, nil
 }
 
 func Register(qd *querydsl.QueryDSL) {
 	qd.AddClauseTypeSummarized(typeKey, SizeProcessor, documentation, SizeSummary)
+	qd.SetClauseCoster(typeKey, SizeCost)
 }
@@ -0,0 +1,113 @@
+package size
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+func TestSizeProcessor(t *testing.T) {
+	cases := []struct {
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{args: map[string]interface{}{"from": "1KB", "to": "2KB"}, expected: elastic.NewRangeQuery("fileSize").Gte(int64(1024)).Lte(int64(2048))},
+		{args: map[string]interface{}{"from": "1KB", "from_exclusive": true}, expected: elastic.NewRangeQuery("fileSize").Gt(int64(1024))},
+		{args: map[string]interface{}{"to": "1MB", "to_exclusive": true}, expected: elastic.NewRangeQuery("fileSize").Lt(int64(1048576))},
+		{args: map[string]interface{}{"from": "1.25 gb", "to": "2GiB"}, expected: elastic.NewRangeQuery("fileSize").Gte(int64(1342177280)).Lte(int64(2147483648))},
+		{args: map[string]interface{}{"size": ">= 1GB"}, expected: elastic.NewRangeQuery("fileSize").Gte(int64(1073741824))},
+		{args: map[string]interface{}{"size": "1KB..2KB"}, expected: elastic.NewRangeQuery("fileSize").Gte(int64(1024)).Lte(int64(2048))},
+		{args: map[string]interface{}{"size": "> 1KB, < 2KB"}, expected: elastic.NewRangeQuery("fileSize").Gt(int64(1024)).Lt(int64(2048))},
+		{args: map[string]interface{}{"size": ">= 1GB", "from": "1KB"}, shouldErr: true},                  // both shorthand and from
+		{args: map[string]interface{}{"size": "2KB..1KB"}, shouldErr: true},                               // inverted
+		{args: map[string]interface{}{"from": "2KB", "to": "1KB"}, shouldErr: true},                       // inverted
+		{args: map[string]interface{}{"from": "1KB", "to": "1KB", "to_exclusive": true}, shouldErr: true}, // empty
+		{args: map[string]interface{}{}, shouldErr: true},                                                 // no bounds
+		{args: map[string]interface{}{"from": "lots"}, shouldErr: true},                                   // bad size
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.args), func(t *testing.T) {
+			query, err := SizeProcessor(context.Background(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("SizeProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("SizeProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Errorf("Source get on expected query failed with error: %q", err)
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestSizeSummary(t *testing.T) {
+	cases := []struct {
+		ctx      context.Context
+		args     map[string]interface{}
+		expected string
+	}{
+		{context.Background(), map[string]interface{}{"from": "1024", "to": "1.5 mb"}, "size=1 KB--1.5 MB"},
+		{context.Background(), map[string]interface{}{"from": "12345", "from_exclusive": true}, "size=(12345 B--]"},
+		{clauseutils.WithSizeUnitSystem(context.Background(), clauseutils.SISizes), map[string]interface{}{"to": "1500000"}, "size=--1.5 MB"},
+		{context.Background(), map[string]interface{}{"to": "lots"}, "size=--lots"},
+		{context.Background(), map[string]interface{}{"size": ">= 1048576"}, "size>=1 MB"},
+		{context.Background(), map[string]interface{}{"size": "1024..2KB"}, "size=1 KB..2 KB"},
+		{context.Background(), map[string]interface{}{"size": "> 1KB, < 1.5KB"}, "size>1 KB,<1.5 KB"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := SizeSummary(c.ctx, c.args)
+			if err != nil {
+				t.Errorf("SizeSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
+
+func TestSizeSummaryRoundTrip(t *testing.T) {
+	for _, expr := range []string{">= 1GB", "< 12345", "1KB..2.5MB", "> 1KB, <= 2KB", "1 PB"} {
+		t.Run(expr, func(t *testing.T) {
+			ctx := context.Background()
+			summary, err := SizeSummary(ctx, map[string]interface{}{"size": expr})
+			if err != nil {
+				t.Fatalf("SizeSummary failed with error: %q", err)
+			}
+			roundTripped := strings.TrimPrefix(strings.TrimPrefix(summary, typeKey), "=")
+
+			original, err := SizeProcessor(ctx, map[string]interface{}{"size": expr})
+			if err != nil {
+				t.Fatalf("SizeProcessor failed with error: %q", err)
+			}
+			fromSummary, err := SizeProcessor(ctx, map[string]interface{}{"size": roundTripped})
+			if err != nil {
+				t.Fatalf("SizeProcessor failed on summary %q with error: %q", summary, err)
+			}
+
+			source, _ := original.Source()
+			summarySource, _ := fromSummary.Source()
+			if !reflect.DeepEqual(source, summarySource) {
+				t.Errorf("Summary %q gave %+v rather than %+v", summary, summarySource, source)
+			}
+		})
+	}
+}
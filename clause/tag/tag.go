@@ -3,9 +3,12 @@ package tag
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/cyverse-de/querydsl/v2"
 	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/olivere/elastic/v7"
 )
@@ -16,38 +19,163 @@ const (
 
 var (
 	documentation = clause.ClauseDocumentation{
-		Summary: "Searches based on a set of provided tag IDs",
+		Summary: "Searches based on a set of provided tag IDs or names",
 		Args: map[string]clause.ClauseArgumentDocumentation{
-			"tags": {Type: "[]string", Summary: "The tag UUIDs to search for"},
+			"tags":  {Type: "[]string", Summary: "The tag UUIDs to search for"},
+			"names": {Type: "[]string", Summary: "The names of tags to search for, looked up with the configured tag resolver. A name may match several tags, each of which counts as its own tag lookup."},
+			"owner": {Type: "string", Summary: "The owner of the tags named in 'names'. Defaults to the current user, or any owner if there is none."},
+			"match": {Type: "string", Summary: "'any' (the default) to search for objects with any of the tags, or 'all' to search for objects with all of them"},
 		},
 	}
 )
 
 type TagArgs struct {
-	Tags []string
+	Tags  []string
+	Names []string
+	Owner string
+	Match string
 }
 
-func TagProcessor(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+// matchAll returns whether objects must have all of the tags, rather than any of them
+func (a TagArgs) matchAll() (bool, error) {
+	switch a.Match {
+	case "", "any":
+		return false, nil
+	case "all":
+		return true, nil
+	}
+	return false, fmt.Errorf("Got a match of %q, but expected any or all", a.Match)
+}
+
+// tagGroups returns the IDs of each tag to search for: one group per tag ID, and one per name holding the IDs of the
+// tags with that name
+func tagGroups(ctx context.Context, realArgs TagArgs) ([][]string, error) {
+	var groups [][]string
+	for _, tag := range realArgs.Tags {
+		groups = append(groups, []string{tag})
+	}
+
+	if len(realArgs.Names) > 0 {
+		owner, err := clauseutils.UserOrCurrent(ctx, realArgs.Owner)
+		if err != nil {
+			owner = ""
+		}
+		for _, name := range realArgs.Names {
+			ids, err := clauseutils.ResolveTags(ctx, name, owner)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, ids)
+		}
+	}
+	return groups, nil
+}
+
+func TagProcessor(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
 	var realArgs TagArgs
 	err := mapstructure.Decode(args, &realArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(realArgs.Tags) == 0 {
+	if len(realArgs.Tags) == 0 && len(realArgs.Names) == 0 {
 		return nil, errors.New("No tags were passed, cannot create clause.")
 	}
 
+	all, err := realArgs.matchAll()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := tagGroups(ctx, realArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	lookupOptions, _ := clauseutils.TagLookupOptionsFromContext(ctx)
+	lookup := func(tag string) elastic.Query {
+		return elastic.NewTermsQuery("id").TermsLookup(lookupOptions.TermsLookup(tag))
+	}
+
 	query := elastic.NewBoolQuery()
 
-	for _, tag := range realArgs.Tags {
-		termsLookup := elastic.NewTermsLookup().Id(tag).Path("targets.id")
-		query.Should(elastic.NewTermsQuery("id").TermsLookup(termsLookup))
+	for _, group := range groups {
+		if !all {
+			for _, tag := range group {
+				query.Should(lookup(tag))
+			}
+			continue
+		}
+
+		// a name may match several tags, any one of which will do
+		if len(group) == 1 {
+			query.Must(lookup(group[0]))
+			continue
+		}
+		anyTag := elastic.NewBoolQuery()
+		for _, tag := range group {
+			anyTag.Should(lookup(tag))
+		}
+		query.Must(anyTag)
 	}
 
 	return query, nil
 }
 
+func TagSummary(ctx context.Context, args map[string]interface{}) (string, error) {
+	var realArgs TagArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if len(realArgs.Tags) == 0 && len(realArgs.Names) == 0 {
+		return "", errors.New("No tags were passed, cannot create clause.")
+	}
+
+	all, err := realArgs.matchAll()
+	if err != nil {
+		return "", err
+	}
+
+	tags := append([]string{}, realArgs.Tags...)
+	for _, name := range realArgs.Names {
+		tags = append(tags, fmt.Sprintf("\"%s\"", name))
+	}
+	summary := fmt.Sprintf("tag=[%s]", strings.Join(tags, ","))
+	if all {
+		summary = fmt.Sprintf("tag=all[%s]", strings.Join(tags, ","))
+	}
+	if realArgs.Owner != "" && len(realArgs.Names) > 0 {
+		summary = fmt.Sprintf("%s(owner=%s)", summary, realArgs.Owner)
+	}
+	return summary, nil
+}
+
+func TagCost(ctx context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	var realArgs TagArgs
+	err := mapstructure.Decode(args, &realArgs)
+	if err != nil {
+		return clause.ClauseCost{}, err
+	}
+
+	// every tag a name resolves to is its own terms lookup. Names which can't be looked up are counted as a single tag
+	// each, since the clause will fail anyway.
+	tags := len(realArgs.Tags) + len(realArgs.Names)
+	if groups, err := tagGroups(ctx, realArgs); err == nil {
+		tags = 0
+		for _, group := range groups {
+			tags += len(group)
+		}
+	}
+	return clause.ClauseCost{
+		Cost:       clauseutils.TermsLookupCost * float64(tags),
+		Terms:      tags,
+		TagLookups: tags,
+	}, nil
+}
+
 func Register(qd *querydsl.QueryDSL) {
-	qd.AddClauseType(typeKey, TagProcessor, documentation)
+	qd.AddClauseTypeSummarized(typeKey, TagProcessor, documentation, TagSummary)
+	qd.SetClauseCoster(typeKey, TagCost)
 }
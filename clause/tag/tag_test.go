@@ -0,0 +1,140 @@
+package tag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
+	"github.com/olivere/elastic/v7"
+)
+
+var testTags = map[string][]string{
+	"ipctest/favorites": {"t1"},
+	"ipctest/project":   {"t2", "t3"},
+	"mian/favorites":    {"t4"},
+}
+
+func testContext() context.Context {
+	resolver := clauseutils.TagResolverFunc(func(_ context.Context, name, owner string) ([]string, error) {
+		return testTags[owner+"/"+name], nil
+	})
+	ctx := clauseutils.WithTagResolver(context.Background(), resolver)
+	ctx = clauseutils.WithTagLookupOptions(ctx, clauseutils.TagLookupOptions{Index: "tags"})
+	return clauseutils.WithCurrentUser(ctx, "ipctest")
+}
+
+func lookup(id string) elastic.Query {
+	return elastic.NewTermsQuery("id").TermsLookup(elastic.NewTermsLookup().Index("tags").Id(id).Path("targets.id"))
+}
+
+func TestTagProcessor(t *testing.T) {
+	cases := []struct {
+		name      string
+		args      map[string]interface{}
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{
+			name:     "ids",
+			args:     map[string]interface{}{"tags": []string{"t1", "t2"}},
+			expected: elastic.NewBoolQuery().Should(lookup("t1"), lookup("t2")),
+		},
+		{
+			name:     "names",
+			args:     map[string]interface{}{"names": []string{"favorites", "project"}},
+			expected: elastic.NewBoolQuery().Should(lookup("t1"), lookup("t2"), lookup("t3")),
+		},
+		{
+			name:     "owner",
+			args:     map[string]interface{}{"names": []string{"favorites"}, "owner": "mian"},
+			expected: elastic.NewBoolQuery().Should(lookup("t4")),
+		},
+		{
+			name: "all",
+			args: map[string]interface{}{"tags": []string{"t4"}, "names": []string{"project"}, "match": "all"},
+			expected: elastic.NewBoolQuery().Must(
+				lookup("t4"),
+				elastic.NewBoolQuery().Should(lookup("t2"), lookup("t3")),
+			),
+		},
+		{name: "unknown name", args: map[string]interface{}{"names": []string{"archive"}}, shouldErr: true},
+		{name: "bad match", args: map[string]interface{}{"tags": []string{"t1"}, "match": "some"}, shouldErr: true},
+		{name: "no tags", args: map[string]interface{}{"match": "all"}, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := TagProcessor(testContext(), c.args)
+			if c.shouldErr && err == nil {
+				t.Errorf("TagProcessor should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("TagProcessor failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Fatalf("Source failed with error: %q", err)
+				}
+				expected, _ := c.expected.Source()
+				if !reflect.DeepEqual(source, expected) {
+					t.Errorf("Got %+v, expected %+v", source, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestTagProcessorNoResolver(t *testing.T) {
+	if _, err := TagProcessor(context.Background(), map[string]interface{}{"names": []string{"favorites"}}); err == nil {
+		t.Error("TagProcessor should have failed to look up names without a tag resolver")
+	}
+}
+
+func TestTagSummary(t *testing.T) {
+	cases := []struct {
+		args     map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"tags": []string{"t1", "t2"}}, `tag=[t1,t2]`},
+		{map[string]interface{}{"tags": []string{"t1"}, "names": []string{"favorites"}, "match": "all"}, `tag=all[t1,"favorites"]`},
+		{map[string]interface{}{"names": []string{"favorites"}, "owner": "mian"}, `tag=["favorites"](owner=mian)`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			summary, err := TagSummary(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("TagSummary failed with error: %q", err)
+			}
+			if summary != c.expected {
+				t.Errorf("Got '%s' from summarize, not '%s'", summary, c.expected)
+			}
+		})
+	}
+}
+
+func TestTagCost(t *testing.T) {
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		args    map[string]interface{}
+		lookups int
+	}{
+		{"ids", testContext(), map[string]interface{}{"tags": []string{"t1", "t2"}}, 2},
+		{"name with one tag", testContext(), map[string]interface{}{"names": []string{"favorites"}}, 1},
+		{"name with several tags", testContext(), map[string]interface{}{"tags": []string{"t1"}, "names": []string{"project"}}, 3},
+		{"unresolved name", context.Background(), map[string]interface{}{"names": []string{"project"}}, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cost, err := TagCost(c.ctx, c.args)
+			if err != nil {
+				t.Fatalf("TagCost failed with error: %q", err)
+			}
+			if cost.TagLookups != c.lookups || cost.Terms != c.lookups || cost.Cost != clauseutils.TermsLookupCost*float64(c.lookups) {
+				t.Errorf("Got %+v, expected %d tag lookups", cost, c.lookups)
+			}
+		})
+	}
+}
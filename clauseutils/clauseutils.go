@@ -2,8 +2,8 @@
 package clauseutils
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,6 +19,11 @@ func AddImplicitWildcard(input string) string {
 		return input
 	}
 
+	return wrapImplicitWildcards(input)
+}
+
+// wrapImplicitWildcards adds wildcards around each piece of input separated by OR or whitespace
+func wrapImplicitWildcards(input string) string {
 	splitRegex := regexp.MustCompile(`( OR |\s+)`)
 	inputSplit := splitRegex.Split(input, -1)
 	var rejoin []string
@@ -33,6 +38,22 @@ func AddImplicitWildcard(input string) string {
 	return strings.Join(rejoin, " ")
 }
 
+// leadingWildcardTokens counts the whitespace-separated parts of a query string starting with a wildcard
+func leadingWildcardTokens(input string) int {
+	var count int
+	for _, part := range strings.Fields(input) {
+		if strings.HasPrefix(part, "*") || strings.HasPrefix(part, "?") {
+			count++
+		}
+	}
+	return count
+}
+
+// LeadingWildcardCount counts how many patterns in a query string will start with a wildcard after AddImplicitWildcard is applied
+func LeadingWildcardCount(input string) int {
+	return leadingWildcardTokens(AddImplicitWildcard(input))
+}
+
 // AddImplicitUsernameWildcard adds '#*' to input usernames which do not already contain a # character (which is the delimiter for qualified iRODS usernames)
 func AddImplicitUsernameWildcard(input string) string {
 	hasdelim := regexp.MustCompile(`[#]`)
@@ -42,6 +63,15 @@ func AddImplicitUsernameWildcard(input string) string {
 	return input + "#*"
 }
 
+// StringsToInterfaces converts a slice of strings to a slice of interface{}, as used by the elastic library's variadic query constructors
+func StringsToInterfaces(input []string) []interface{} {
+	output := make([]interface{}, len(input))
+	for i, s := range input {
+		output[i] = s
+	}
+	return output
+}
+
 // DateToEpochMs converts a string date to milliseconds since epoch. Expects either string-wrapped number of milliseconds or YYYY-MM-DDTHH:MM:SS.mssTZ format.
 func DateToEpochMs(date string) (int64, error) {
 	var err error
@@ -63,31 +93,9 @@ func DateToEpochMs(date string) (int64, error) {
 	return t.UnixNano() / 1000000, nil
 }
 
-// StringToFilesize converts a string to a filesize. Expects either string-wrapped number of bytes or N (K|M|G|T)B, where units are powers of 1024 bytes.
+// StringToFilesize converts a string to a filesize. It accepts anything ParseFilesize does, with KB, MB, and so on as powers of 1024 bytes.
 func StringToFilesize(filesize string) (int64, error) {
-	if ms, err := strconv.ParseInt(filesize, 10, 64); err == nil {
-		return ms, nil
-	}
-
-	matcher := regexp.MustCompile(`^\s*(\d+(?:\.\d)?)\s*(K|M|G|T)B\s*$`)
-	if !matcher.MatchString(filesize) {
-		return 0, fmt.Errorf("Provided string \"%s\" does not describe a filesize", filesize)
-	}
-
-	match := matcher.FindStringSubmatch(filesize)
-	fsNum, _ := strconv.ParseFloat(match[1], 64)
-	switch match[2] {
-	case "K":
-		return int64(1024 * fsNum), nil
-	case "M":
-		return int64(math.Pow(1024, 2) * fsNum), nil
-	case "G":
-		return int64(math.Pow(1024, 3) * fsNum), nil
-	case "T":
-		return int64(math.Pow(1024, 4) * fsNum), nil
-	}
-
-	return 0, fmt.Errorf("Somehow fell through to the end of the function")
+	return ParseFilesize(filesize, LegacyBinarySizes)
 }
 
 // RangeType specifies what sort of range to create for CreateRangeQuery
@@ -103,7 +111,7 @@ const (
 )
 
 // CreateRangeQuery creates a simple range query for a field, and integer lower/upper limits, plus a RangeType to specify behavior
-// Range values are int64s since this stuff deals with large numbers
+// Range values are int64s since this stuff deals with large numbers. Use a RangeBuilder for exclusive bounds or other types of values.
 func CreateRangeQuery(field string, rangetype RangeType, lower int64, upper int64) elastic.Query {
 	rq := elastic.NewRangeQuery(field)
 	if rangetype == Both || rangetype == UpperOnly {
@@ -114,3 +122,141 @@ func CreateRangeQuery(field string, rangetype RangeType, lower int64, upper int6
 	}
 	return rq
 }
+
+// rangeBound is one end of a RangeBuilder's range
+type rangeBound struct {
+	value     interface{}
+	exclusive bool
+	set       bool
+}
+
+// RangeBuilder builds a range query from optional, inclusive or exclusive bounds, rejecting ranges that cannot match anything.
+// Bounds may be int64, int, float64, or time.Time values; times are sent as milliseconds since epoch.
+type RangeBuilder struct {
+	field string
+	lower rangeBound
+	upper rangeBound
+}
+
+// NewRangeBuilder creates a RangeBuilder for a field, with neither bound set
+func NewRangeBuilder(field string) *RangeBuilder {
+	return &RangeBuilder{field: field}
+}
+
+// From sets the lower bound, using gt rather than gte if exclusive is set
+func (b *RangeBuilder) From(value interface{}, exclusive bool) *RangeBuilder {
+	b.lower = rangeBound{value: value, exclusive: exclusive, set: true}
+	return b
+}
+
+// To sets the upper bound, using lt rather than lte if exclusive is set
+func (b *RangeBuilder) To(value interface{}, exclusive bool) *RangeBuilder {
+	b.upper = rangeBound{value: value, exclusive: exclusive, set: true}
+	return b
+}
+
+// normalizeRangeValue converts a bound to the value sent to Elasticsearch
+func normalizeRangeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64, float64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case time.Time:
+		return v.UnixNano() / int64(time.Millisecond), nil
+	}
+	return nil, fmt.Errorf("Range bounds cannot be of type %T", value)
+}
+
+// compareRangeValues compares two normalized bounds, returning -1, 0, or 1 as a is less than, equal to, or greater than b
+func compareRangeValues(a, b interface{}) int {
+	ai, aIsInt := a.(int64)
+	bi, bIsInt := b.(int64)
+	if aIsInt && bIsInt {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+
+	af, bf := toFloat(a), toFloat(b)
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value interface{}) float64 {
+	if i, ok := value.(int64); ok {
+		return float64(i)
+	}
+	return value.(float64)
+}
+
+// Build creates the range query, failing if no bound is set, a bound is of an unsupported type, or the range is
+// inverted or otherwise empty
+func (b *RangeBuilder) Build() (elastic.Query, error) {
+	if !b.lower.set && !b.upper.set {
+		return nil, errors.New("Neither from nor to was passed, cannot create clause.")
+	}
+
+	rq := elastic.NewRangeQuery(b.field)
+	var lower, upper interface{}
+	var err error
+
+	if b.lower.set {
+		if lower, err = normalizeRangeValue(b.lower.value); err != nil {
+			return nil, err
+		}
+		if b.lower.exclusive {
+			rq.Gt(lower)
+		} else {
+			rq.Gte(lower)
+		}
+	}
+
+	if b.upper.set {
+		if upper, err = normalizeRangeValue(b.upper.value); err != nil {
+			return nil, err
+		}
+		if b.upper.exclusive {
+			rq.Lt(upper)
+		} else {
+			rq.Lte(upper)
+		}
+	}
+
+	if b.lower.set && b.upper.set {
+		cmp := compareRangeValues(lower, upper)
+		if cmp > 0 {
+			return nil, fmt.Errorf("The start of the range (%v) is after its end (%v), so it cannot match anything.", b.lower.value, b.upper.value)
+		}
+		if cmp == 0 && (b.lower.exclusive || b.upper.exclusive) {
+			return nil, fmt.Errorf("The range excludes its only value (%v), so it cannot match anything.", b.lower.value)
+		}
+	}
+
+	return rq, nil
+}
+
+// RangeSummary summarizes a range clause as name=from--to, bracketing the range in interval notation if either bound is exclusive
+func RangeSummary(name, from, to string, fromExclusive, toExclusive bool) string {
+	interval := fmt.Sprintf("%s--%s", from, to)
+	if fromExclusive || toExclusive {
+		open, close := "[", "]"
+		if fromExclusive {
+			open = "("
+		}
+		if toExclusive {
+			close = ")"
+		}
+		interval = open + interval + close
+	}
+	return fmt.Sprintf("%s=%s", name, interval)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/olivere/elastic/v7"
 )
@@ -144,3 +145,74 @@ func TestCreateRangeQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestRangeBuilder(t *testing.T) {
+	day := time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC)
+	dayMs := day.UnixNano() / int64(time.Millisecond)
+
+	cases := []struct {
+		name      string
+		builder   *RangeBuilder
+		expected  elastic.Query
+		shouldErr bool
+	}{
+		{"inclusive", NewRangeBuilder("meh").From(int64(0), false).To(int64(10), false), elastic.NewRangeQuery("meh").Gte(int64(0)).Lte(int64(10)), false},
+		{"exclusive", NewRangeBuilder("meh").From(int64(0), true).To(int64(10), true), elastic.NewRangeQuery("meh").Gt(int64(0)).Lt(int64(10)), false},
+		{"half-open", NewRangeBuilder("meh").From(int64(5), true), elastic.NewRangeQuery("meh").Gt(int64(5)), false},
+		{"int", NewRangeBuilder("meh").To(10, false), elastic.NewRangeQuery("meh").Lte(int64(10)), false},
+		{"float", NewRangeBuilder("meh").From(20.5, false).To(30.0, true), elastic.NewRangeQuery("meh").Gte(20.5).Lt(30.0), false},
+		{"mixed", NewRangeBuilder("meh").From(int64(20), false).To(20.5, false), elastic.NewRangeQuery("meh").Gte(int64(20)).Lte(20.5), false},
+		{"date", NewRangeBuilder("meh").From(day, false).To(day.Add(time.Hour), false), elastic.NewRangeQuery("meh").Gte(dayMs).Lte(dayMs + 3600000), false},
+		{"single value", NewRangeBuilder("meh").From(int64(10), false).To(int64(10), false), elastic.NewRangeQuery("meh").Gte(int64(10)).Lte(int64(10)), false},
+		{"no bounds", NewRangeBuilder("meh"), nil, true},
+		{"inverted", NewRangeBuilder("meh").From(int64(10), false).To(int64(0), false), nil, true},
+		{"inverted float", NewRangeBuilder("meh").From(1.5, false).To(int64(1), false), nil, true},
+		{"inverted date", NewRangeBuilder("meh").From(day, false).To(day.Add(-time.Millisecond), false), nil, true},
+		{"excluded single value", NewRangeBuilder("meh").From(int64(10), true).To(int64(10), false), nil, true},
+		{"bad type", NewRangeBuilder("meh").From("10", false), nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := c.builder.Build()
+			if c.shouldErr && err == nil {
+				t.Errorf("Build should have failed, instead returned nil error and query %+v", query)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("Build failed with error: %q", err)
+			} else if !c.shouldErr {
+				source, err := query.Source()
+				if err != nil {
+					t.Error("Source get on built range query failed")
+				}
+				expsource, err := c.expected.Source()
+				if err != nil {
+					t.Error("Source get on expected range query failed")
+				}
+				if !reflect.DeepEqual(source, expsource) {
+					t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeSummary(t *testing.T) {
+	cases := []struct {
+		from, to                   string
+		fromExclusive, toExclusive bool
+		expected                   string
+	}{
+		{"1KB", "1MB", false, false, "size=1KB--1MB"},
+		{"1KB", "", true, false, "size=(1KB--]"},
+		{"", "1MB", false, true, "size=[--1MB)"},
+		{"1KB", "1MB", true, true, "size=(1KB--1MB)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			if summary := RangeSummary("size", c.from, c.to, c.fromExclusive, c.toExclusive); summary != c.expected {
+				t.Errorf("Got %q but expected %q", summary, c.expected)
+			}
+		})
+	}
+}
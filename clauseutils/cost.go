@@ -0,0 +1,67 @@
+package clauseutils
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/cyverse-de/querydsl/v2/clause"
+)
+
+// Relative costs used when estimating how expensive clauses are, in units of a single term query
+const (
+	TermCost            = 1.0
+	RangeCost           = 2.0
+	PrefixCost          = 2.0
+	NestedCost          = 2.0
+	WildcardCost        = 5.0
+	TermsLookupCost     = 10.0
+	LeadingWildcardCost = 25.0
+)
+
+// CountTerms counts the terms in a set of clause args, treating each element of a list argument as a term and every other argument as none
+func CountTerms(args map[string]interface{}) int {
+	var terms int
+	for _, arg := range args {
+		v := reflect.ValueOf(arg)
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			terms += v.Len()
+		}
+	}
+	return terms
+}
+
+// DefaultClauseCost is the ClauseCoster used for clause types without their own, costing a single term query per term
+func DefaultClauseCost(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+	terms := CountTerms(args)
+	if terms == 0 {
+		terms = 1
+	}
+	return clause.ClauseCost{Cost: TermCost * float64(terms), Terms: terms}, nil
+}
+
+// QueryStringCost estimates the cost of a query_string query for input, which has AddImplicitWildcard applied unless exact is set
+func QueryStringCost(input string, exact bool) clause.ClauseCost {
+	if !exact {
+		input = AddImplicitWildcard(input)
+	}
+	terms := len(strings.Fields(input))
+	leading := leadingWildcardTokens(input)
+	return clause.ClauseCost{
+		Cost:             TermCost*float64(terms-leading) + LeadingWildcardCost*float64(leading),
+		Terms:            terms,
+		LeadingWildcards: leading,
+	}
+}
+
+// AddCosts sums a set of clause costs
+func AddCosts(costs ...clause.ClauseCost) clause.ClauseCost {
+	var total clause.ClauseCost
+	for _, cost := range costs {
+		total.Cost += cost.Cost
+		total.Terms += cost.Terms
+		total.LeadingWildcards += cost.LeadingWildcards
+		total.TagLookups += cost.TagLookups
+	}
+	return total
+}
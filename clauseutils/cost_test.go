@@ -0,0 +1,56 @@
+package clauseutils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cyverse-de/querydsl/v2/clause"
+)
+
+func TestQueryStringCost(t *testing.T) {
+	cases := []struct {
+		input    string
+		exact    bool
+		expected clause.ClauseCost
+	}{
+		{"foo", false, clause.ClauseCost{Cost: LeadingWildcardCost, Terms: 1, LeadingWildcards: 1}},
+		{"foo bar", false, clause.ClauseCost{Cost: 2 * LeadingWildcardCost, Terms: 2, LeadingWildcards: 2}},
+		{"foo bar", true, clause.ClauseCost{Cost: 2 * TermCost, Terms: 2}},
+		{"*foo bar", true, clause.ClauseCost{Cost: TermCost + LeadingWildcardCost, Terms: 2, LeadingWildcards: 1}},
+		{"foo* bar?", false, clause.ClauseCost{Cost: 2 * TermCost, Terms: 2}},
+		{"", false, clause.ClauseCost{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			cost := QueryStringCost(c.input, c.exact)
+			if cost != c.expected {
+				t.Errorf("QueryStringCost returned %+v instead of expected %+v", cost, c.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultClauseCost(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     map[string]interface{}
+		expected clause.ClauseCost
+	}{
+		{"empty", map[string]interface{}{}, clause.ClauseCost{Cost: TermCost, Terms: 1}},
+		{"scalar", map[string]interface{}{"label": "foo"}, clause.ClauseCost{Cost: TermCost, Terms: 1}},
+		{"lists", map[string]interface{}{"users": []string{"a", "b"}, "groups": []interface{}{"c"}}, clause.ClauseCost{Cost: 3 * TermCost, Terms: 3}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cost, err := DefaultClauseCost(context.Background(), c.args)
+			if err != nil {
+				t.Errorf("DefaultClauseCost failed with error: %q", err)
+			}
+			if cost != c.expected {
+				t.Errorf("DefaultClauseCost returned %+v instead of expected %+v", cost, c.expected)
+			}
+		})
+	}
+}
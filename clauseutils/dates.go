@@ -0,0 +1,308 @@
+package clauseutils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock returns the current time, for evaluating relative dates such as "now-7d"
+type Clock func() time.Time
+
+type clockKey struct{}
+
+// WithClock returns a copy of ctx carrying the given Clock, which relative dates are evaluated against instead of the system clock
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ClockFromContext returns the Clock carried by ctx, if any
+func ClockFromContext(ctx context.Context) (Clock, bool) {
+	clock, ok := ctx.Value(clockKey{}).(Clock)
+	return clock, ok
+}
+
+// Now returns the current time according to the Clock carried by ctx, or the system clock if there is none
+func Now(ctx context.Context) time.Time {
+	if clock, ok := ClockFromContext(ctx); ok {
+		return clock()
+	}
+	return time.Now()
+}
+
+// DateRounding says which way a rounded date, such as "now/d", resolves
+type DateRounding int
+
+const (
+	// RoundDown resolves rounded dates to their first millisecond, as Elasticsearch does for inclusive lower bounds
+	RoundDown DateRounding = iota
+	// RoundUp resolves rounded dates to their last millisecond, as Elasticsearch does for inclusive upper bounds
+	RoundUp
+)
+
+// dateKeywords maps the natural-language dates ParseDate understands to date math
+var dateKeywords = map[string]string{
+	"now":       "now",
+	"today":     "now/d",
+	"yesterday": "now-1d/d",
+	"tomorrow":  "now+1d/d",
+}
+
+// dateUnits maps the names used in "start of" and "end of" dates to date math units
+var dateUnits = map[string]byte{
+	"minute": 'm',
+	"hour":   'h',
+	"day":    'd',
+	"week":   'w',
+	"month":  'M',
+	"year":   'y',
+}
+
+// addDateUnits adds n of the given date math unit to t
+func addDateUnits(t time.Time, n int, unit byte) (time.Time, error) {
+	switch unit {
+	case 'y':
+		return t.AddDate(n, 0, 0), nil
+	case 'M':
+		return t.AddDate(0, n, 0), nil
+	case 'w':
+		return t.AddDate(0, 0, 7*n), nil
+	case 'd':
+		return t.AddDate(0, 0, n), nil
+	case 'h', 'H':
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case 'm':
+		return t.Add(time.Duration(n) * time.Minute), nil
+	case 's':
+		return t.Add(time.Duration(n) * time.Second), nil
+	}
+	return t, fmt.Errorf("Unknown date math unit %q", unit)
+}
+
+// roundDate rounds t down to the start of the given date math unit, or up to its last millisecond.
+// Weeks start on Monday, as in Elasticsearch.
+func roundDate(t time.Time, unit byte, rounding DateRounding) (time.Time, error) {
+	var start time.Time
+	switch unit {
+	case 'y':
+		start = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	case 'M':
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case 'w':
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		start = time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	case 'd':
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case 'h', 'H':
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case 'm':
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	case 's':
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	default:
+		return t, fmt.Errorf("Unknown date math unit %q", unit)
+	}
+	if rounding == RoundDown {
+		return start, nil
+	}
+	end, err := addDateUnits(start, 1, unit)
+	if err != nil {
+		return t, err
+	}
+	return end.Add(-time.Millisecond), nil
+}
+
+// dateLayouts are the formats parseAbsoluteDate tries, from most to least precise, with the date math unit of each one's precision
+var dateLayouts = []struct {
+	layout    string
+	precision byte
+}{
+	{"2006-01-02T15:04:05Z07:00", 's'},
+	{"2006-01-02T15:04Z07:00", 'm'},
+	{"2006-01-02T15:04:05", 's'},
+	{"2006-01-02T15:04", 'm'},
+	{"2006-01-02T15", 'h'},
+	{"2006-01-02", 'd'},
+	{"2006-01", 'M'},
+	{"2006", 'y'},
+}
+
+// isYear returns whether date is a four-digit year, rather than milliseconds since epoch
+func isYear(date string) bool {
+	return len(date) == 4 && strings.Trim(date, "0123456789") == ""
+}
+
+// parseAbsoluteDate parses a date in one of the dateLayouts, as milliseconds since epoch, or as seconds since epoch
+// prefixed with @. Dates without a time zone are in loc. It returns the date math unit of the date's precision, or 0 if
+// it is precise to the millisecond.
+func parseAbsoluteDate(date string, loc *time.Location) (time.Time, byte, error) {
+	if ms, err := strconv.ParseInt(date, 10, 64); err == nil && !isYear(date) {
+		return time.Unix(0, ms*int64(time.Millisecond)).In(loc), 0, nil
+	}
+	if strings.HasPrefix(date, "@") {
+		secs, err := strconv.ParseFloat(date[1:], 64)
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("Could not parse seconds since epoch in %q", date)
+		}
+		// rounded, since a fraction like .015 isn't exact as a float
+		ms := int64(math.Round(secs * 1000))
+		return time.Unix(0, ms*int64(time.Millisecond)).In(loc), 0, nil
+	}
+
+	// allow a space between the date and time
+	if len(date) > 10 && date[10] == ' ' {
+		date = date[:10] + "T" + date[11:]
+	}
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l.layout, date, loc)
+		if err != nil {
+			continue
+		}
+		precision := l.precision
+		// fractional seconds are allowed after any layout with seconds
+		if precision == 's' && strings.Contains(date[len("2006-01-02T15:04:05"):], ".") {
+			precision = 0
+		}
+		return t, precision, nil
+	}
+	return time.Time{}, 0, fmt.Errorf("Could not parse the date %q", date)
+}
+
+// LoadTimezone returns the time zone with the given IANA name, such as America/Phoenix, or UTC offset, such as -07:00 or +0530.
+// An empty name is UTC.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Z" {
+		return time.UTC, nil
+	}
+	for _, layout := range []string{"-07:00", "-0700", "-07"} {
+		if t, err := time.Parse(layout, name); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(name, offset), nil
+		}
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// applyDateMath applies Elasticsearch date math operations, such as "-7d/d", to t
+func applyDateMath(t time.Time, expr string, rounding DateRounding) (time.Time, error) {
+	var err error
+	for i := 0; i < len(expr); {
+		op := expr[i]
+		i++
+		switch op {
+		case '+', '-':
+			start := i
+			for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+				i++
+			}
+			n := 1
+			if i > start {
+				n, err = strconv.Atoi(expr[start:i])
+				if err != nil {
+					return t, err
+				}
+			}
+			if op == '-' {
+				n = -n
+			}
+			if i >= len(expr) {
+				return t, fmt.Errorf("Missing unit after %q in date math", expr[:i])
+			}
+			t, err = addDateUnits(t, n, expr[i])
+		case '/':
+			if i >= len(expr) {
+				return t, fmt.Errorf("Missing unit after %q in date math", expr[:i])
+			}
+			t, err = roundDate(t, expr[i], rounding)
+		default:
+			return t, fmt.Errorf("Unexpected %q in date math %q", op, expr)
+		}
+		if err != nil {
+			return t, err
+		}
+		i++
+	}
+	return t, nil
+}
+
+// ParseDate converts a date to milliseconds since epoch, using ParseDateIn with UTC as the time zone
+func ParseDate(ctx context.Context, date string, rounding DateRounding) (int64, error) {
+	return ParseDateIn(ctx, date, rounding, time.UTC)
+}
+
+// ParseDateIn converts a date to milliseconds since epoch. It accepts milliseconds since epoch, seconds since epoch
+// prefixed with @, RFC3339 dates with or without fractional seconds, and dates in the YYYY-MM-DDTHH:MM:SS, YYYY-MM-DDTHH:MM,
+// YYYY-MM-DDTHH, YYYY-MM-DD, YYYY-MM, and YYYY formats, with a space allowed in place of the T; dates without a time zone
+// are in loc. Dates less precise than a millisecond resolve according to rounding, so a "to" of 2017-09-23 includes
+// all of that day. It also understands Elasticsearch date math anchored on "now" or on a date followed by "||", such as "now-7d/d" or "2017-09-23||+1M";
+// "-1w" and "+2d" as shorthand for date math relative to now; "now", "today", "yesterday", and "tomorrow"; and
+// "start of" or "end of" followed by minute, hour, day, week, month, or year. Rounding, and the days named by keywords,
+// resolve according to rounding, so a "to" of "today" includes all of today. Relative dates are evaluated against the
+// Clock carried by ctx, if any, with rounding in loc.
+func ParseDateIn(ctx context.Context, date string, rounding DateRounding, loc *time.Location) (int64, error) {
+	trimmed := strings.TrimSpace(date)
+	lower := strings.ToLower(trimmed)
+
+	// negative milliseconds since epoch aren't relative
+	if ms, err := strconv.ParseInt(trimmed, 10, 64); err == nil && !isYear(trimmed) {
+		return ms, nil
+	}
+
+	if expr, ok := dateKeywords[lower]; ok {
+		trimmed = expr
+	} else if strings.HasPrefix(lower, "start of ") || strings.HasPrefix(lower, "end of ") {
+		words := strings.Fields(lower)
+		unit, ok := dateUnits[words[len(words)-1]]
+		if len(words) != 3 || !ok {
+			return 0, fmt.Errorf("Could not understand the date %q", date)
+		}
+		// start and end choose their own rounding, whichever bound they're used as
+		rounding = RoundDown
+		if words[0] == "end" {
+			rounding = RoundUp
+		}
+		trimmed = "now/" + string(unit)
+	} else if strings.HasPrefix(trimmed, "-") || strings.HasPrefix(trimmed, "+") {
+		trimmed = "now" + trimmed
+	}
+
+	var anchor time.Time
+	var expr string
+	switch {
+	case strings.HasPrefix(trimmed, "now"):
+		anchor = Now(ctx).In(loc)
+		expr = trimmed[len("now"):]
+	case strings.Contains(trimmed, "||"):
+		parts := strings.SplitN(trimmed, "||", 2)
+		var err error
+		anchor, _, err = parseAbsoluteDate(parts[0], loc)
+		if err != nil {
+			return 0, err
+		}
+		expr = parts[1]
+	default:
+		t, precision, err := parseAbsoluteDate(trimmed, loc)
+		if err != nil {
+			return 0, err
+		}
+		if precision != 0 {
+			if t, err = roundDate(t, precision, rounding); err != nil {
+				return 0, err
+			}
+		}
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+
+	t, err := applyDateMath(anchor, expr, rounding)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
@@ -0,0 +1,141 @@
+package clauseutils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2017, 9, 20, 15, 30, 45, 123000000, time.UTC)
+	ctx := WithClock(context.Background(), func() time.Time { return now })
+	ms := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+
+	cases := []struct {
+		input     string
+		rounding  DateRounding
+		expected  int64
+		shouldErr bool
+	}{
+		{input: "1506124800000", expected: 1506124800000},
+		{input: "-1000", expected: -1000},
+		{input: "2017-09-23", expected: ms(time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC))},
+		{input: "now", expected: ms(now)},
+		{input: "now-7d", expected: ms(now.AddDate(0, 0, -7))},
+		{input: "-1w", expected: ms(now.AddDate(0, 0, -7))},
+		{input: "+2h", expected: ms(now.Add(2 * time.Hour))},
+		{input: "now-1M/M", expected: ms(time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC))},
+		{input: "now-1M/M", rounding: RoundUp, expected: ms(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "now/w", expected: ms(time.Date(2017, 9, 18, 0, 0, 0, 0, time.UTC))},
+		{input: "now/d+1h", expected: ms(time.Date(2017, 9, 20, 1, 0, 0, 0, time.UTC))},
+		{input: "now-d", expected: ms(now.AddDate(0, 0, -1))},
+		{input: "2017-09-23||+1M", expected: ms(time.Date(2017, 10, 23, 0, 0, 0, 0, time.UTC))},
+		{input: "2017-09-23||/y", rounding: RoundUp, expected: ms(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "today", expected: ms(time.Date(2017, 9, 20, 0, 0, 0, 0, time.UTC))},
+		{input: "Today", rounding: RoundUp, expected: ms(time.Date(2017, 9, 21, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "yesterday", expected: ms(time.Date(2017, 9, 19, 0, 0, 0, 0, time.UTC))},
+		{input: "tomorrow", rounding: RoundUp, expected: ms(time.Date(2017, 9, 22, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "start of month", rounding: RoundUp, expected: ms(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC))},
+		{input: "end of year", expected: ms(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: " start of  week ", expected: ms(time.Date(2017, 9, 18, 0, 0, 0, 0, time.UTC))},
+		{input: "2017-09-23T00:00:00Z", expected: ms(time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC))},
+		{input: "2017-09-23T00:00:00Z", rounding: RoundUp, expected: ms(time.Date(2017, 9, 23, 0, 0, 1, 0, time.UTC)) - 1},
+		{input: "2017-09-23T00:00:00.000Z", rounding: RoundUp, expected: ms(time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC))},
+		{input: "2017-09-23T10:00:00.250-07:00", expected: ms(time.Date(2017, 9, 23, 17, 0, 0, 250000000, time.UTC))},
+		{input: "2017-09-23", rounding: RoundUp, expected: ms(time.Date(2017, 9, 24, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "2017-09-23T10", rounding: RoundUp, expected: ms(time.Date(2017, 9, 23, 11, 0, 0, 0, time.UTC)) - 1},
+		{input: "2017-09-23 10:30", expected: ms(time.Date(2017, 9, 23, 10, 30, 0, 0, time.UTC))},
+		{input: "2017-09", expected: ms(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC))},
+		{input: "2017-02", rounding: RoundUp, expected: ms(time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "2017", rounding: RoundUp, expected: ms(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)) - 1},
+		{input: "@1506124800", expected: 1506124800000},
+		{input: "@1506124800.5", expected: 1506124800500},
+		{input: "@1.015", expected: 1015},
+		{input: "@-1.015", expected: -1015},
+		{input: "@soon", shouldErr: true},
+		{input: "2017-09-23T25", shouldErr: true},
+		{input: "09/23/2017", shouldErr: true},
+		{input: "start of fortnight", shouldErr: true},
+		{input: "now-7", shouldErr: true},
+		{input: "now-7x", shouldErr: true},
+		{input: "now*2d", shouldErr: true},
+		{input: "now/", shouldErr: true},
+		{input: "2017-13-01||+1d", shouldErr: true},
+		{input: "last tuesday", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue, err := ParseDate(ctx, c.input, c.rounding)
+			if c.shouldErr && err == nil {
+				t.Errorf("ParseDate should have failed, instead returned %d", gotValue)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ParseDate failed with error: %q", err)
+			} else if !c.shouldErr && gotValue != c.expected {
+				t.Errorf("Got %d but expected %d", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestParseDateIn(t *testing.T) {
+	now := time.Date(2017, 9, 20, 3, 0, 0, 0, time.UTC)
+	ctx := WithClock(context.Background(), func() time.Time { return now })
+	ms := func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+	phoenix := time.FixedZone("-07:00", -7*60*60)
+
+	cases := []struct {
+		input    string
+		rounding DateRounding
+		expected int64
+	}{
+		{"2017-09-23", RoundDown, ms(time.Date(2017, 9, 23, 0, 0, 0, 0, phoenix))},
+		{"2017-09-23", RoundUp, ms(time.Date(2017, 9, 24, 0, 0, 0, 0, phoenix)) - 1},
+		{"2017-09-23T00:00:00Z", RoundDown, ms(time.Date(2017, 9, 23, 0, 0, 0, 0, time.UTC))},
+		// 03:00 UTC is still the 19th in Phoenix
+		{"today", RoundDown, ms(time.Date(2017, 9, 19, 0, 0, 0, 0, phoenix))},
+		{"2017-09-23||/M", RoundUp, ms(time.Date(2017, 10, 1, 0, 0, 0, 0, phoenix)) - 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue, err := ParseDateIn(ctx, c.input, c.rounding, phoenix)
+			if err != nil {
+				t.Errorf("ParseDateIn failed with error: %q", err)
+			} else if gotValue != c.expected {
+				t.Errorf("Got %d but expected %d", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	cases := []struct {
+		input     string
+		offset    int
+		shouldErr bool
+	}{
+		{input: "", offset: 0},
+		{input: "UTC", offset: 0},
+		{input: "-07:00", offset: -7 * 60 * 60},
+		{input: "+0530", offset: 5*60*60 + 30*60},
+		{input: "+09", offset: 9 * 60 * 60},
+		{input: "Mars/Olympus_Mons", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			loc, err := LoadTimezone(c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("LoadTimezone should have failed, instead returned %v", loc)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("LoadTimezone failed with error: %q", err)
+			} else if !c.shouldErr {
+				if _, offset := time.Date(2017, 1, 1, 0, 0, 0, 0, loc).Zone(); offset != c.offset {
+					t.Errorf("Got offset %d but expected %d", offset, c.offset)
+				}
+			}
+		})
+	}
+}
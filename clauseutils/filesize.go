@@ -0,0 +1,127 @@
+package clauseutils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// SizeUnitSystem decides what the decimal-looking size units, such as KB and MB, mean
+type SizeUnitSystem int
+
+const (
+	// LegacyBinarySizes treats KB, MB, and so on as powers of 1024 bytes, as this package always has
+	LegacyBinarySizes SizeUnitSystem = iota
+	// SISizes treats KB, MB, and so on as powers of 1000 bytes
+	SISizes
+)
+
+type sizeUnitSystemKey struct{}
+
+// WithSizeUnitSystem returns a copy of ctx carrying the given SizeUnitSystem
+func WithSizeUnitSystem(ctx context.Context, system SizeUnitSystem) context.Context {
+	return context.WithValue(ctx, sizeUnitSystemKey{}, system)
+}
+
+// SizeUnitSystemFromContext returns the SizeUnitSystem carried by ctx, if any
+func SizeUnitSystemFromContext(ctx context.Context) (SizeUnitSystem, bool) {
+	system, ok := ctx.Value(sizeUnitSystemKey{}).(SizeUnitSystem)
+	return system, ok
+}
+
+// sizeUnitPrefixes are the size unit prefixes in increasing order, each 1000 or 1024 times the last
+var sizeUnitPrefixes = []string{"k", "m", "g", "t", "p", "e"}
+
+var filesizeMatcher = regexp.MustCompile(`^\s*(\d+(?:\.\d*)?|\.\d+)\s*([a-zA-Z]*)\s*$`)
+
+// sizeMultiplier returns the number of bytes in a unit such as "KiB", "mb", "G", or "bytes"
+func sizeMultiplier(unit string, system SizeUnitSystem) (*big.Int, error) {
+	unit = strings.ToLower(unit)
+	switch unit {
+	case "", "b", "byte", "bytes":
+		return big.NewInt(1), nil
+	}
+
+	for i, prefix := range sizeUnitPrefixes {
+		var base int64
+		switch unit {
+		case prefix, prefix + "b":
+			base = 1000
+			if system == LegacyBinarySizes {
+				base = 1024
+			}
+		case prefix + "ib":
+			base = 1024
+		default:
+			continue
+		}
+		return new(big.Int).Exp(big.NewInt(base), big.NewInt(int64(i+1)), nil), nil
+	}
+	return nil, fmt.Errorf("Unknown size unit %q", unit)
+}
+
+// ParseFilesize converts a string to a number of bytes. It accepts a number of bytes, or a number with any number of
+// decimal places followed by optional whitespace and a unit: B or bytes; KiB, MiB, GiB, TiB, PiB, or EiB, which are
+// powers of 1024 bytes; or KB, MB, GB, TB, PB, or EB (or just K, M, and so on), which are powers of 1000 or 1024
+// bytes depending on system. Units are case-insensitive. Fractions of a byte are dropped, and sizes too large for an
+// int64 are rejected.
+func ParseFilesize(filesize string, system SizeUnitSystem) (int64, error) {
+	match := filesizeMatcher.FindStringSubmatch(filesize)
+	if match == nil {
+		return 0, fmt.Errorf("Provided string \"%s\" does not describe a filesize", filesize)
+	}
+
+	number, ok := new(big.Rat).SetString(match[1])
+	if !ok {
+		return 0, fmt.Errorf("Provided string \"%s\" does not describe a filesize", filesize)
+	}
+	multiplier, err := sizeMultiplier(match[2], system)
+	if err != nil {
+		return 0, err
+	}
+
+	bytes := new(big.Int).Quo(new(big.Int).Mul(number.Num(), multiplier), number.Denom())
+	if !bytes.IsInt64() {
+		return 0, fmt.Errorf("Provided filesize \"%s\" is too large", filesize)
+	}
+	return bytes.Int64(), nil
+}
+
+// FilesizeFromContext converts a string to a number of bytes with ParseFilesize, using the SizeUnitSystem carried by ctx
+func FilesizeFromContext(ctx context.Context, filesize string) (int64, error) {
+	system, _ := SizeUnitSystemFromContext(ctx)
+	return ParseFilesize(filesize, system)
+}
+
+// FormatFilesize formats a number of bytes for display, such as "1.25 GB", using the largest unit of system that
+// represents it exactly with at most two decimal places, so ParseFilesize with the same system gives back the same size
+func FormatFilesize(size int64, system SizeUnitSystem) string {
+	base := int64(1000)
+	if system == LegacyBinarySizes {
+		base = 1024
+	}
+
+	for i := len(sizeUnitPrefixes) - 1; i >= 0 && size > 0; i-- {
+		unit := int64(math.Pow(float64(base), float64(i+1)))
+		if size < unit {
+			continue
+		}
+		// the remainder is a whole number of hundredths when it's a multiple of unit/gcd(unit, 100), which avoids overflowing
+		gcd := new(big.Int).GCD(nil, nil, big.NewInt(unit), big.NewInt(100)).Int64()
+		step := unit / gcd
+		remainder := size % unit
+		if remainder%step != 0 {
+			continue
+		}
+		whole, hundredths := size/unit, remainder/step*(100/gcd)
+		label := strings.ToUpper(sizeUnitPrefixes[i]) + "B"
+		if hundredths == 0 {
+			return fmt.Sprintf("%d %s", whole, label)
+		}
+		return strings.TrimRight(fmt.Sprintf("%d.%02d", whole, hundredths), "0") + " " + label
+	}
+	return fmt.Sprintf("%d B", size)
+}
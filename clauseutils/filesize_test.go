@@ -0,0 +1,98 @@
+package clauseutils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestParseFilesize(t *testing.T) {
+	cases := []struct {
+		input     string
+		system    SizeUnitSystem
+		expected  int64
+		shouldErr bool
+	}{
+		{input: "12345", expected: 12345},
+		{input: "10 bytes", expected: 10},
+		{input: "10B", expected: 10},
+		{input: "1.25GB", expected: 1342177280},
+		{input: "1.25GB", system: SISizes, expected: 1250000000},
+		{input: "500 kb", expected: 512000},
+		{input: "500 kb", system: SISizes, expected: 500000},
+		{input: "2GiB", expected: 2147483648},
+		{input: "2gib", system: SISizes, expected: 2147483648},
+		{input: "1 PB", expected: 1125899906842624},
+		{input: "1 PB", system: SISizes, expected: 1000000000000000},
+		{input: "1 EiB", expected: 1152921504606846976},
+		{input: "7.99 EB", system: SISizes, expected: 7990000000000000000},
+		{input: "1.5k", expected: 1536},
+		{input: ".5 MB", expected: 524288},
+		{input: "3. KB", expected: 3072},
+		{input: "1.0001 KB", expected: 1024},
+		{input: "  2 TB  ", expected: 2199023255552},
+		{input: "8 EiB", shouldErr: true},               // too large
+		{input: "9223372036854775808", shouldErr: true}, // bigger than int64
+		{input: "1 XB", shouldErr: true},
+		{input: "1 KiBB", shouldErr: true},
+		{input: "-1 KB", shouldErr: true},
+		{input: "1,000 KB", shouldErr: true},
+		{input: "", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s(%d)", c.input, c.system), func(t *testing.T) {
+			val, err := ParseFilesize(c.input, c.system)
+			if c.shouldErr && err == nil {
+				t.Errorf("ParseFilesize should have failed, instead returned %d", val)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ParseFilesize failed with error: %q", err)
+			} else if !c.shouldErr && val != c.expected {
+				t.Errorf("ParseFilesize returned %d instead of expected %d", val, c.expected)
+			}
+		})
+	}
+}
+
+func TestFilesizeFromContext(t *testing.T) {
+	ctx := WithSizeUnitSystem(context.Background(), SISizes)
+	if val, err := FilesizeFromContext(ctx, "1 KB"); err != nil || val != 1000 {
+		t.Errorf("FilesizeFromContext returned %d, %v with SI sizes", val, err)
+	}
+	if val, err := FilesizeFromContext(context.Background(), "1 KB"); err != nil || val != 1024 {
+		t.Errorf("FilesizeFromContext returned %d, %v by default", val, err)
+	}
+}
+
+func TestFormatFilesize(t *testing.T) {
+	cases := []struct {
+		input    int64
+		system   SizeUnitSystem
+		expected string
+	}{
+		{0, LegacyBinarySizes, "0 B"},
+		{500, LegacyBinarySizes, "500 B"},
+		{1024, LegacyBinarySizes, "1 KB"},
+		{1000, SISizes, "1 KB"},
+		{1342177280, LegacyBinarySizes, "1.25 GB"},
+		{1250000000, SISizes, "1.25 GB"},
+		{1572864, LegacyBinarySizes, "1.5 MB"},
+		{12345, LegacyBinarySizes, "12345 B"},
+		{1536, LegacyBinarySizes, "1.5 KB"},
+		{1152921504606846976, LegacyBinarySizes, "1 EB"},
+		{9223372036854775807, LegacyBinarySizes, "9223372036854775807 B"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			formatted := FormatFilesize(c.input, c.system)
+			if formatted != c.expected {
+				t.Errorf("Got %q but expected %q", formatted, c.expected)
+			}
+			parsed, err := ParseFilesize(formatted, c.system)
+			if err != nil || parsed != c.input {
+				t.Errorf("%q parsed back as %d, %v rather than %d", formatted, parsed, err, c.input)
+			}
+		})
+	}
+}
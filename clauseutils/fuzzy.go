@@ -0,0 +1,50 @@
+package clauseutils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// AutoFuzziness lets Elasticsearch choose an edit distance based on the length of each term
+const AutoFuzziness = "AUTO"
+
+var autoFuzzinessMatcher = regexp.MustCompile(`^AUTO(?::\d+,\d+)?$`)
+
+// ParseFuzziness converts a clause argument into an Elasticsearch fuzziness value, and whether fuzzy matching was requested at all.
+// Accepts nil or false (not fuzzy), true (AUTO), 'AUTO' or 'AUTO:low,high', or an edit distance of 0, 1, or 2 as a number or string.
+func ParseFuzziness(value interface{}) (string, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", false, nil
+	case bool:
+		if v {
+			return AutoFuzziness, true, nil
+		}
+		return "", false, nil
+	case string:
+		if v == "" {
+			return "", false, nil
+		}
+		if autoFuzzinessMatcher.MatchString(v) {
+			return v, true, nil
+		}
+		distance, err := strconv.Atoi(v)
+		if err != nil {
+			return "", false, fmt.Errorf("Got a fuzziness of %q, but expected AUTO or an edit distance of 0, 1, or 2", v)
+		}
+		return ParseFuzziness(distance)
+	case int:
+		if v < 0 || v > 2 {
+			return "", false, fmt.Errorf("Got a fuzziness of %d, but expected an edit distance of 0, 1, or 2", v)
+		}
+		return strconv.Itoa(v), true, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return "", false, fmt.Errorf("Got a fuzziness of %g, but expected a whole number edit distance", v)
+		}
+		return ParseFuzziness(int(v))
+	}
+	return "", false, fmt.Errorf("Got a fuzziness of %v, but expected a boolean, AUTO, or an edit distance", value)
+}
@@ -0,0 +1,42 @@
+package clauseutils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFuzziness(t *testing.T) {
+	cases := []struct {
+		input         interface{}
+		expected      string
+		expectedFuzzy bool
+		shouldErr     bool
+	}{
+		{nil, "", false, false},
+		{false, "", false, false},
+		{"", "", false, false},
+		{true, "AUTO", true, false},
+		{"AUTO", "AUTO", true, false},
+		{"AUTO:3,6", "AUTO:3,6", true, false},
+		{1, "1", true, false},
+		{float64(2), "2", true, false},
+		{"0", "0", true, false},
+		{3, "", false, true},
+		{1.5, "", false, true},
+		{"auto-ish", "", false, true},
+		{[]string{"AUTO"}, "", false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%T(%v)", c.input, c.input), func(t *testing.T) {
+			val, fuzzy, err := ParseFuzziness(c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("ParseFuzziness should have failed, instead returned %q", val)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ParseFuzziness failed with error: %q", err)
+			} else if !c.shouldErr && (val != c.expected || fuzzy != c.expectedFuzzy) {
+				t.Errorf("ParseFuzziness returned %q, %t instead of expected %q, %t", val, fuzzy, c.expected, c.expectedFuzzy)
+			}
+		})
+	}
+}
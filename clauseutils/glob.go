@@ -0,0 +1,94 @@
+package clauseutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// GlobToRegexp converts a path glob into a Lucene regexp matching entire paths. A * matches any characters except /,
+// a ? matches a single character except /, a ** path component matches any number of directories (including none),
+// and [...] or [!...] match a character from (or not from) a set. Everything else matches literally.
+func GlobToRegexp(glob string) (string, error) {
+	if glob == "" {
+		return "", errors.New("No glob was passed")
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				atComponentStart := i == 0 || glob[i-1] == '/'
+				if !atComponentStart {
+					return "", errors.New("** may only be used as an entire path component")
+				}
+				switch {
+				case strings.HasPrefix(glob[i:], "**/"):
+					b.WriteString("(.*/)?")
+					i += 2
+				case i+2 == len(glob):
+					b.WriteString(".*")
+					i++
+				default:
+					return "", errors.New("** may only be used as an entire path component")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := i + 1
+			negated := j < len(glob) && glob[j] == '!'
+			if negated {
+				j++
+			}
+			var set strings.Builder
+			// a ] directly after [ or [! is part of the set
+			for first := true; j < len(glob) && (first || glob[j] != ']'); j++ {
+				first = false
+				switch glob[j] {
+				case '-':
+					// a range, unless it starts or ends the set
+					if set.Len() > 0 && j+1 < len(glob) && glob[j+1] != ']' {
+						set.WriteByte('-')
+					} else {
+						set.WriteString(`\-`)
+					}
+				case '\\', ']', '[', '^':
+					set.WriteByte('\\')
+					set.WriteByte(glob[j])
+				default:
+					set.WriteByte(glob[j])
+				}
+			}
+			if j >= len(glob) {
+				return "", errors.New("Unterminated character set in glob")
+			}
+			b.WriteByte('[')
+			if negated {
+				b.WriteByte('^')
+			}
+			b.WriteString(set.String())
+			b.WriteByte(']')
+			i = j
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(EscapeRegexp(glob[i : i+1]))
+		default:
+			b.WriteString(EscapeRegexp(glob[i : i+1]))
+		}
+	}
+	return b.String(), nil
+}
+
+// GlobLiteralPrefix returns the part of a glob before its first special character, which every matching path starts with
+func GlobLiteralPrefix(glob string) string {
+	if i := strings.IndexAny(glob, `*?[\`); i >= 0 {
+		return glob[:i]
+	}
+	return glob
+}
@@ -0,0 +1,57 @@
+package clauseutils
+
+import (
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{input: "/iplant/home/*/analyses/**/*.bam", expected: `/iplant/home/[^/]*/analyses/(.*/)?[^/]*\.bam`},
+		{input: "/iplant/home/foo/**", expected: `/iplant/home/foo/.*`},
+		{input: "**/file?.txt", expected: `(.*/)?file[^/]\.txt`},
+		{input: "/a/[abc]/[!0-9]", expected: `/a/[abc]/[^0-9]`},
+		{input: "/a/[]-]", expected: `/a/[\]\-]`},
+		{input: `/a/\*(1)@x`, expected: `/a/\*\(1\)\@x`},
+		{input: "", shouldErr: true},
+		{input: "/a/[abc", shouldErr: true},
+		{input: "/a/b**", shouldErr: true},
+		{input: "/a/**b", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			pattern, err := GlobToRegexp(c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("GlobToRegexp should have failed, instead returned %q", pattern)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("GlobToRegexp failed with error: %q", err)
+			} else if !c.shouldErr && pattern != c.expected {
+				t.Errorf("Got %q but expected %q", pattern, c.expected)
+			}
+		})
+	}
+}
+
+func TestGlobLiteralPrefix(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"/iplant/home/*/analyses", "/iplant/home/"},
+		{"/iplant/home/foo", "/iplant/home/foo"},
+		{"*.bam", ""},
+		{"/a/b?", "/a/b"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if prefix := GlobLiteralPrefix(c.input); prefix != c.expected {
+				t.Errorf("Got %q but expected %q", prefix, c.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyverse-de/querydsl/v2/clause"
+)
+
+// DefaultMaxGroupMembers is the maximum number of usernames a set of groups may expand to when no other limit is configured
+const DefaultMaxGroupMembers = 1000
+
+// ErrTooManyGroupMembers is returned when a set of groups expands to more members than allowed
+var ErrTooManyGroupMembers = errors.New("groups expanded to too many members")
+
+// GroupResolver looks up the usernames belonging to a group
+type GroupResolver interface {
+	GroupMembers(ctx context.Context, group string) ([]string, error)
+}
+
+// GroupResolverFunc adapts a function into a GroupResolver
+type GroupResolverFunc func(ctx context.Context, group string) ([]string, error)
+
+// GroupMembers implements GroupResolver
+func (f GroupResolverFunc) GroupMembers(ctx context.Context, group string) ([]string, error) {
+	return f(ctx, group)
+}
+
+// GroupMembersResult is the eventual result of an asynchronous group lookup
+type GroupMembersResult struct {
+	Members []string
+	Err     error
+}
+
+// AsyncGroupResolver looks up the members of a group in the background, delivering a single result on the returned channel
+type AsyncGroupResolver interface {
+	GroupMembersAsync(ctx context.Context, group string) <-chan GroupMembersResult
+}
+
+type asyncGroupResolver struct {
+	resolver AsyncGroupResolver
+}
+
+// GroupMembers implements GroupResolver, waiting for the asynchronous result or for ctx to be done
+func (r asyncGroupResolver) GroupMembers(ctx context.Context, group string) ([]string, error) {
+	select {
+	case result := <-r.resolver.GroupMembersAsync(ctx, group):
+		return result.Members, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FromAsyncGroupResolver adapts an AsyncGroupResolver into a GroupResolver
+func FromAsyncGroupResolver(resolver AsyncGroupResolver) GroupResolver {
+	return asyncGroupResolver{resolver: resolver}
+}
+
+type groupCacheEntry struct {
+	members []string
+	expires time.Time
+}
+
+// CachingGroupResolver wraps another GroupResolver, remembering successful lookups for a fixed duration
+type CachingGroupResolver struct {
+	resolver GroupResolver
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]groupCacheEntry
+}
+
+// NewCachingGroupResolver creates a CachingGroupResolver which keeps results from resolver for ttl
+func NewCachingGroupResolver(resolver GroupResolver, ttl time.Duration) *CachingGroupResolver {
+	return &CachingGroupResolver{resolver: resolver, ttl: ttl, now: time.Now, entries: make(map[string]groupCacheEntry)}
+}
+
+// GroupMembers implements GroupResolver
+func (r *CachingGroupResolver) GroupMembers(ctx context.Context, group string) ([]string, error) {
+	r.mu.Lock()
+	entry, exists := r.entries[group]
+	r.mu.Unlock()
+	if exists && r.now().Before(entry.expires) {
+		return entry.members, nil
+	}
+
+	members, err := r.resolver.GroupMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.entries[group] = groupCacheEntry{members: members, expires: r.now().Add(r.ttl)}
+	r.mu.Unlock()
+	return members, nil
+}
+
+type groupResolverKey struct{}
+type maxGroupMembersKey struct{}
+
+// WithGroupResolver returns a copy of ctx carrying the given GroupResolver
+func WithGroupResolver(ctx context.Context, resolver GroupResolver) context.Context {
+	return context.WithValue(ctx, groupResolverKey{}, resolver)
+}
+
+// GroupResolverFromContext returns the GroupResolver carried by ctx, if any
+func GroupResolverFromContext(ctx context.Context) (GroupResolver, bool) {
+	resolver, ok := ctx.Value(groupResolverKey{}).(GroupResolver)
+	return resolver, ok
+}
+
+// WithMaxGroupMembers returns a copy of ctx carrying a limit on the number of usernames groups may expand to
+func WithMaxGroupMembers(ctx context.Context, max int) context.Context {
+	return context.WithValue(ctx, maxGroupMembersKey{}, max)
+}
+
+// MaxGroupMembersFromContext returns the group expansion limit carried by ctx, if any
+func MaxGroupMembersFromContext(ctx context.Context) (int, bool) {
+	max, ok := ctx.Value(maxGroupMembersKey{}).(int)
+	return max, ok
+}
+
+// GroupMembersCost estimates the cost of matching the members of a set of groups. The groups are expanded with the
+// GroupResolver carried by ctx and charged one term for each member, as a wildcard, as unqualified usernames are by
+// default. Groups which can't be expanded are charged as the most members allowed by the limit carried by ctx, or
+// DefaultMaxGroupMembers.
+func GroupMembersCost(ctx context.Context, groups []string) clause.ClauseCost {
+	if len(groups) == 0 {
+		return clause.ClauseCost{}
+	}
+
+	expanded, err := ExpandGroups(ctx, groups)
+	members := len(expanded)
+	if err != nil {
+		max, ok := MaxGroupMembersFromContext(ctx)
+		if !ok {
+			max = DefaultMaxGroupMembers
+		}
+		members = max
+	}
+	return clause.ClauseCost{Cost: WildcardCost * float64(members), Terms: members}
+}
+
+// ExpandGroups looks up the members of each group concurrently using the GroupResolver carried by ctx, returning the distinct usernames found.
+// It fails if the groups expand to more usernames than the limit carried by ctx, or DefaultMaxGroupMembers.
+func ExpandGroups(ctx context.Context, groups []string) ([]string, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	resolver, ok := GroupResolverFromContext(ctx)
+	if !ok {
+		return nil, errors.New("No group resolver is configured, cannot expand groups")
+	}
+
+	max, ok := MaxGroupMembersFromContext(ctx)
+	if !ok {
+		max = DefaultMaxGroupMembers
+	}
+
+	results := make([]GroupMembersResult, len(groups))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func(i int, group string) {
+			defer wg.Done()
+			members, err := resolver.GroupMembers(ctx, group)
+			if err != nil {
+				err = fmt.Errorf("Failed to look up members of group %q: %w", group, err)
+			}
+			results[i] = GroupMembersResult{Members: members, Err: err}
+		}(i, group)
+	}
+	wg.Wait()
+
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		for _, member := range result.Members {
+			if !seen[member] {
+				seen[member] = true
+				expanded = append(expanded, member)
+			}
+		}
+		if len(expanded) > max {
+			return nil, fmt.Errorf("%w: more than %d usernames", ErrTooManyGroupMembers, max)
+		}
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,147 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var testGroups = map[string][]string{
+	"lab":     {"mian", "ipctest"},
+	"friends": {"ipctest", "foo#bar"},
+	"empty":   {},
+}
+
+func testGroupResolver(calls *int) GroupResolver {
+	return GroupResolverFunc(func(_ context.Context, group string) ([]string, error) {
+		if calls != nil {
+			*calls++
+		}
+		members, ok := testGroups[group]
+		if !ok {
+			return nil, errors.New("no such group")
+		}
+		return members, nil
+	})
+}
+
+type testAsyncGroupResolver struct{}
+
+func (testAsyncGroupResolver) GroupMembersAsync(ctx context.Context, group string) <-chan GroupMembersResult {
+	results := make(chan GroupMembersResult, 1)
+	go func() {
+		members, err := testGroupResolver(nil).GroupMembers(ctx, group)
+		results <- GroupMembersResult{Members: members, Err: err}
+	}()
+	return results
+}
+
+func TestExpandGroups(t *testing.T) {
+	cases := []struct {
+		name      string
+		resolver  GroupResolver
+		max       int
+		groups    []string
+		expected  []string
+		shouldErr bool
+	}{
+		{name: "none", resolver: testGroupResolver(nil), groups: nil, expected: nil},
+		{name: "single", resolver: testGroupResolver(nil), groups: []string{"lab"}, expected: []string{"ipctest", "mian"}},
+		{name: "deduplicated", resolver: testGroupResolver(nil), groups: []string{"lab", "friends"}, expected: []string{"foo#bar", "ipctest", "mian"}},
+		{name: "async", resolver: FromAsyncGroupResolver(testAsyncGroupResolver{}), groups: []string{"lab", "friends"}, expected: []string{"foo#bar", "ipctest", "mian"}},
+		{name: "empty", resolver: testGroupResolver(nil), groups: []string{"empty"}, expected: nil},
+		{name: "limit", resolver: testGroupResolver(nil), max: 2, groups: []string{"lab", "friends"}, shouldErr: true},
+		{name: "unknown", resolver: testGroupResolver(nil), groups: []string{"nope"}, shouldErr: true},
+		{name: "no-resolver", groups: []string{"lab"}, shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.resolver != nil {
+				ctx = WithGroupResolver(ctx, c.resolver)
+			}
+			if c.max > 0 {
+				ctx = WithMaxGroupMembers(ctx, c.max)
+			}
+
+			members, err := ExpandGroups(ctx, c.groups)
+			sort.Strings(members)
+			if c.shouldErr && err == nil {
+				t.Errorf("ExpandGroups should have failed, instead returned %v", members)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ExpandGroups failed with error: %q", err)
+			} else if !c.shouldErr && !reflect.DeepEqual(members, c.expected) {
+				t.Errorf("ExpandGroups returned %v instead of expected %v", members, c.expected)
+			}
+		})
+	}
+}
+
+func TestExpandGroupsLimitError(t *testing.T) {
+	ctx := WithMaxGroupMembers(WithGroupResolver(context.Background(), testGroupResolver(nil)), 1)
+	_, err := ExpandGroups(ctx, []string{"lab"})
+	if !errors.Is(err, ErrTooManyGroupMembers) {
+		t.Errorf("ExpandGroups returned %v rather than an ErrTooManyGroupMembers", err)
+	}
+}
+
+func TestCachingGroupResolver(t *testing.T) {
+	var calls int
+	now := time.Unix(0, 0)
+	resolver := NewCachingGroupResolver(testGroupResolver(&calls), time.Minute)
+	resolver.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.GroupMembers(context.Background(), "lab"); err != nil {
+			t.Fatalf("GroupMembers failed with error: %q", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Underlying resolver was called %d times rather than once", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := resolver.GroupMembers(context.Background(), "lab"); err != nil {
+		t.Fatalf("GroupMembers failed with error: %q", err)
+	}
+	if calls != 2 {
+		t.Errorf("Underlying resolver was called %d times rather than twice after expiry", calls)
+	}
+
+	if _, err := resolver.GroupMembers(context.Background(), "nope"); err == nil {
+		t.Error("GroupMembers should have failed for an unknown group")
+	}
+}
+
+func TestGroupMembersCost(t *testing.T) {
+	if cost := GroupMembersCost(context.Background(), nil); cost.Terms != 0 || cost.Cost != 0 {
+		t.Errorf("No groups cost %+v rather than nothing", cost)
+	}
+	if cost := GroupMembersCost(context.Background(), []string{"lab"}); cost.Terms != DefaultMaxGroupMembers || cost.Cost != WildcardCost*DefaultMaxGroupMembers {
+		t.Errorf("A group cost %+v rather than the default member limit", cost)
+	}
+	if cost := GroupMembersCost(WithMaxGroupMembers(context.Background(), 10), []string{"lab", "friends"}); cost.Terms != 10 {
+		t.Errorf("Groups cost %+v rather than the configured member limit of 10 terms", cost)
+	}
+
+	resolver := GroupResolverFunc(func(_ context.Context, group string) ([]string, error) {
+		if group == "lab" {
+			return []string{"a#iplant", "b#iplant"}, nil
+		}
+		return nil, errors.New("unknown group")
+	})
+	ctx := WithGroupResolver(context.Background(), resolver)
+	if cost := GroupMembersCost(ctx, []string{"lab"}); cost.Terms != 2 || cost.Cost != WildcardCost*2 {
+		t.Errorf("A group of two cost %+v rather than two terms", cost)
+	}
+	if cost := GroupMembersCost(WithMaxGroupMembers(ctx, 1), []string{"lab"}); cost.Terms != 1 {
+		t.Errorf("A group over the member limit cost %+v rather than the limit of 1 term", cost)
+	}
+	if cost := GroupMembersCost(ctx, []string{"nope"}); cost.Terms != DefaultMaxGroupMembers {
+		t.Errorf("An unknown group cost %+v rather than the default member limit", cost)
+	}
+}
@@ -0,0 +1,136 @@
+package clauseutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MetadataNamespace describes one kind of metadata indexed on objects as nested AVU documents, such as metadata.irods
+type MetadataNamespace struct {
+	// Name is how the namespace is referred to in queries, such as in the metadata_types argument of the metadata clause
+	Name string
+	// Path is the path of the nested AVU documents. Defaults to metadata.<Name>.
+	Path string
+	// AttributeField, ValueField, and UnitField are the fields of the AVU documents. They default to attribute, value,
+	// and unit under Path. The value field should have numeric and date subfields for typed value searches.
+	AttributeField string
+	ValueField     string
+	UnitField      string
+	// AttributeKeywordField is the unanalyzed field of AVU attributes, which exact attribute existence checks use. Defaults
+	// to the keyword subfield of AttributeField.
+	AttributeKeywordField string
+	// Documentation describes the metadata in the namespace
+	Documentation string
+}
+
+// NestedPath returns the path of the namespace's nested AVU documents
+func (ns MetadataNamespace) NestedPath() string {
+	if ns.Path == "" {
+		return "metadata." + ns.Name
+	}
+	return ns.Path
+}
+
+func (ns MetadataNamespace) field(field, name string) string {
+	if field == "" {
+		return ns.NestedPath() + "." + name
+	}
+	return field
+}
+
+// Attribute returns the field holding AVU attributes in the namespace
+func (ns MetadataNamespace) Attribute() string {
+	return ns.field(ns.AttributeField, "attribute")
+}
+
+// AttributeKeyword returns the unanalyzed field holding AVU attributes in the namespace
+func (ns MetadataNamespace) AttributeKeyword() string {
+	if ns.AttributeKeywordField == "" {
+		return ns.Attribute() + ".keyword"
+	}
+	return ns.AttributeKeywordField
+}
+
+// Value returns the field holding AVU values in the namespace
+func (ns MetadataNamespace) Value() string {
+	return ns.field(ns.ValueField, "value")
+}
+
+// Unit returns the field holding AVU units in the namespace
+func (ns MetadataNamespace) Unit() string {
+	return ns.field(ns.UnitField, "unit")
+}
+
+// MetadataNamespaces is an ordered registry of metadata namespaces
+type MetadataNamespaces []MetadataNamespace
+
+// DefaultMetadataNamespaces are the namespaces searched when no others are configured
+var DefaultMetadataNamespaces = MetadataNamespaces{
+	{Name: "irods", Documentation: "AVUs set directly in iRODS"},
+	{Name: "cyverse", Documentation: "AVUs set through the CyVerse metadata service, including those filled in from metadata templates"},
+}
+
+// Lookup returns the namespace with the given name, if there is one
+func (n MetadataNamespaces) Lookup(name string) (MetadataNamespace, bool) {
+	for _, ns := range n {
+		if ns.Name == name {
+			return ns, true
+		}
+	}
+	return MetadataNamespace{}, false
+}
+
+// Names returns the names of the namespaces, in order
+func (n MetadataNamespaces) Names() []string {
+	names := make([]string, len(n))
+	for i, ns := range n {
+		names[i] = ns.Name
+	}
+	return names
+}
+
+// Select returns the namespaces with the given names, in registry order, or all of them if no names are given.
+// Unknown names are an error.
+func (n MetadataNamespaces) Select(names []string) (MetadataNamespaces, error) {
+	if len(names) == 0 {
+		return n, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := n.Lookup(name); !ok {
+			return nil, fmt.Errorf("Got a metadata type of %q, but expected one of %s", name, strings.Join(n.Names(), ", "))
+		}
+		wanted[name] = true
+	}
+
+	var selected MetadataNamespaces
+	for _, ns := range n {
+		if wanted[ns.Name] {
+			selected = append(selected, ns)
+		}
+	}
+	return selected, nil
+}
+
+type metadataNamespacesKey struct{}
+
+// WithMetadataNamespaces returns a copy of ctx carrying the given metadata namespaces
+func WithMetadataNamespaces(ctx context.Context, namespaces MetadataNamespaces) context.Context {
+	return context.WithValue(ctx, metadataNamespacesKey{}, namespaces)
+}
+
+// MetadataNamespacesFromContext returns the metadata namespaces carried by ctx, if any
+func MetadataNamespacesFromContext(ctx context.Context) (MetadataNamespaces, bool) {
+	namespaces, ok := ctx.Value(metadataNamespacesKey{}).(MetadataNamespaces)
+	return namespaces, ok
+}
+
+// MetadataNamespacesOrDefault returns the metadata namespaces carried by ctx, or DefaultMetadataNamespaces if there are none
+func MetadataNamespacesOrDefault(ctx context.Context) MetadataNamespaces {
+	if namespaces, ok := MetadataNamespacesFromContext(ctx); ok {
+		return namespaces
+	}
+	return DefaultMetadataNamespaces
+}
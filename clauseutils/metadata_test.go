@@ -0,0 +1,50 @@
+package clauseutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadataNamespaceFields(t *testing.T) {
+	ns := MetadataNamespace{Name: "ontology", ValueField: "annotations.term"}
+	if ns.NestedPath() != "metadata.ontology" {
+		t.Errorf("Got nested path %q, expected metadata.ontology", ns.NestedPath())
+	}
+	if ns.Attribute() != "metadata.ontology.attribute" || ns.Value() != "annotations.term" || ns.Unit() != "metadata.ontology.unit" {
+		t.Errorf("Got fields %q, %q, and %q for %+v", ns.Attribute(), ns.Value(), ns.Unit(), ns)
+	}
+	if ns.AttributeKeyword() != "metadata.ontology.attribute.keyword" {
+		t.Errorf("Got attribute keyword field %q, expected metadata.ontology.attribute.keyword", ns.AttributeKeyword())
+	}
+	ns.AttributeKeywordField = "annotations.term_id"
+	if ns.AttributeKeyword() != "annotations.term_id" {
+		t.Errorf("Got attribute keyword field %q, expected annotations.term_id", ns.AttributeKeyword())
+	}
+}
+
+func TestSelectMetadataNamespaces(t *testing.T) {
+	namespaces := append(MetadataNamespaces{}, DefaultMetadataNamespaces...)
+	namespaces = append(namespaces, MetadataNamespace{Name: "ontology"})
+
+	cases := []struct {
+		names     []string
+		expected  []string
+		shouldErr bool
+	}{
+		{nil, []string{"irods", "cyverse", "ontology"}, false},
+		{[]string{"ontology", "irods"}, []string{"irods", "ontology"}, false},
+		{[]string{"cyverse", "cyverse"}, []string{"cyverse"}, false},
+		{[]string{"irods", "avus"}, nil, true},
+	}
+
+	for _, c := range cases {
+		selected, err := namespaces.Select(c.names)
+		if c.shouldErr && err == nil {
+			t.Errorf("Select(%v) should have failed, instead returned %v", c.names, selected.Names())
+		} else if !c.shouldErr && err != nil {
+			t.Errorf("Select(%v) failed with error: %q", c.names, err)
+		} else if !c.shouldErr && !reflect.DeepEqual(selected.Names(), c.expected) {
+			t.Errorf("Select(%v) returned %v instead of expected %v", c.names, selected.Names(), c.expected)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package clauseutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RangeExpression is a range written in shorthand, such as ">= 1GB", "2017-01-01..2017-12-31", or "< now-30d".
+// Bounds are left as strings, for clauses to parse as sizes, dates, and so on.
+type RangeExpression struct {
+	From          string
+	To            string
+	FromExclusive bool
+	ToExclusive   bool
+}
+
+// RangeExpressionDocumentation describes the shorthand ParseRangeExpression accepts, for clause documentation
+const RangeExpressionDocumentation = "A range in shorthand, instead of from and to: a comparison such as '>= x', '> x', '<= x', or '< x'; a range such as 'x..y', 'x..', or '..y', inclusive of both ends; a single value, matching only that value; or several of these separated by commas, such as '> x, <= y'."
+
+func (r *RangeExpression) setFrom(value string, exclusive bool, expr string) error {
+	if r.From != "" {
+		return fmt.Errorf("The range %q has more than one lower bound", expr)
+	}
+	r.From, r.FromExclusive = value, exclusive
+	return nil
+}
+
+func (r *RangeExpression) setTo(value string, exclusive bool, expr string) error {
+	if r.To != "" {
+		return fmt.Errorf("The range %q has more than one upper bound", expr)
+	}
+	r.To, r.ToExclusive = value, exclusive
+	return nil
+}
+
+// rangeOperators are the comparisons ParseRangeExpression understands, longest first so >= isn't read as >
+var rangeOperators = []string{">=", "<=", ">", "<", "="}
+
+// ParseRangeExpression parses a range in shorthand, as described by RangeExpressionDocumentation
+func ParseRangeExpression(expr string) (RangeExpression, error) {
+	var r RangeExpression
+	if strings.TrimSpace(expr) == "" {
+		return r, fmt.Errorf("No range was passed")
+	}
+
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+
+		op := ""
+		for _, candidate := range rangeOperators {
+			if strings.HasPrefix(term, candidate) {
+				op = candidate
+				term = strings.TrimSpace(term[len(candidate):])
+				break
+			}
+		}
+		if term == "" {
+			return r, fmt.Errorf("The range %q is missing a value", expr)
+		}
+
+		var err error
+		switch {
+		case op == ">=" || op == ">":
+			err = r.setFrom(term, op == ">", expr)
+		case op == "<=" || op == "<":
+			err = r.setTo(term, op == "<", expr)
+		case op == "" && strings.Contains(term, ".."):
+			parts := strings.SplitN(term, "..", 2)
+			from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+			if from == "" && to == "" {
+				return r, fmt.Errorf("The range %q is missing a value", expr)
+			}
+			if from != "" {
+				err = r.setFrom(from, false, expr)
+			}
+			if to != "" && err == nil {
+				err = r.setTo(to, false, expr)
+			}
+		default:
+			if err = r.setFrom(term, false, expr); err == nil {
+				err = r.setTo(term, false, expr)
+			}
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// String formats the range in the shorthand ParseRangeExpression accepts, preferring the shortest form
+func (r RangeExpression) String() string {
+	switch {
+	case r.From != "" && r.From == r.To && !r.FromExclusive && !r.ToExclusive:
+		return r.From
+	case r.From != "" && r.To != "" && !r.FromExclusive && !r.ToExclusive:
+		return fmt.Sprintf("%s..%s", r.From, r.To)
+	}
+
+	var terms []string
+	if r.From != "" {
+		op := ">="
+		if r.FromExclusive {
+			op = ">"
+		}
+		terms = append(terms, op+r.From)
+	}
+	if r.To != "" {
+		op := "<="
+		if r.ToExclusive {
+			op = "<"
+		}
+		terms = append(terms, op+r.To)
+	}
+	return strings.Join(terms, ",")
+}
+
+// Summary summarizes a clause named name searching this range, such as size>=1 GB or created=2017-01-01..2017-12-31,
+// which gives back the range when the name (and any =) is removed and the rest passed to ParseRangeExpression
+func (r RangeExpression) Summary(name string) string {
+	expr := r.String()
+	if strings.HasPrefix(expr, ">") || strings.HasPrefix(expr, "<") {
+		return name + expr
+	}
+	return name + "=" + expr
+}
+
+// RangeExpressionArg takes a range in shorthand out of args[key], returning it parsed (or nil if it wasn't passed)
+// along with the rest of the args
+func RangeExpressionArg(args map[string]interface{}, key string) (*RangeExpression, map[string]interface{}, error) {
+	value, ok := args[key]
+	if !ok {
+		return nil, args, nil
+	}
+
+	expr, ok := value.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("The %s argument must be a string, not %T", key, value)
+	}
+	r, err := ParseRangeExpression(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rest := make(map[string]interface{}, len(args)-1)
+	for k, v := range args {
+		if k != key {
+			rest[k] = v
+		}
+	}
+	return &r, rest, nil
+}
@@ -0,0 +1,100 @@
+package clauseutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRangeExpression(t *testing.T) {
+	cases := []struct {
+		input     string
+		expected  RangeExpression
+		canonical string
+		shouldErr bool
+	}{
+		{input: ">= 1GB", expected: RangeExpression{From: "1GB"}, canonical: ">=1GB"},
+		{input: ">1GB", expected: RangeExpression{From: "1GB", FromExclusive: true}, canonical: ">1GB"},
+		{input: "<= now-30d", expected: RangeExpression{To: "now-30d"}, canonical: "<=now-30d"},
+		{input: "< now-30d", expected: RangeExpression{To: "now-30d", ToExclusive: true}, canonical: "<now-30d"},
+		{input: "2017-01-01..2017-12-31", expected: RangeExpression{From: "2017-01-01", To: "2017-12-31"}, canonical: "2017-01-01..2017-12-31"},
+		{input: "1.5 GB .. 2 GB", expected: RangeExpression{From: "1.5 GB", To: "2 GB"}, canonical: "1.5 GB..2 GB"},
+		{input: ".5KB..", expected: RangeExpression{From: ".5KB"}, canonical: ">=.5KB"},
+		{input: "..2017", expected: RangeExpression{To: "2017"}, canonical: "<=2017"},
+		{input: "2017-09-23", expected: RangeExpression{From: "2017-09-23", To: "2017-09-23"}, canonical: "2017-09-23"},
+		{input: "= 1KB", expected: RangeExpression{From: "1KB", To: "1KB"}, canonical: "1KB"},
+		{input: "> 1GB, <= 2GB", expected: RangeExpression{From: "1GB", FromExclusive: true, To: "2GB"}, canonical: ">1GB,<=2GB"},
+		{input: "< 2GB, >= 1GB", expected: RangeExpression{From: "1GB", To: "2GB", ToExclusive: true}, canonical: ">=1GB,<2GB"},
+		{input: "", shouldErr: true},
+		{input: ">=", shouldErr: true},
+		{input: "..", shouldErr: true},
+		{input: "> 1GB, > 2GB", shouldErr: true},
+		{input: "1GB..2GB, < 3GB", shouldErr: true},
+		{input: "1GB, ", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			r, err := ParseRangeExpression(c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("ParseRangeExpression should have failed, instead returned %+v", r)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ParseRangeExpression failed with error: %q", err)
+			} else if !c.shouldErr {
+				if !reflect.DeepEqual(r, c.expected) {
+					t.Errorf("Got %+v but expected %+v", r, c.expected)
+				}
+				if r.String() != c.canonical {
+					t.Errorf("Formatted as %q rather than %q", r.String(), c.canonical)
+				}
+				reparsed, err := ParseRangeExpression(r.String())
+				if err != nil || !reflect.DeepEqual(reparsed, r) {
+					t.Errorf("%q parsed back as %+v, %v", r.String(), reparsed, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeExpressionSummary(t *testing.T) {
+	cases := []struct {
+		r        RangeExpression
+		expected string
+	}{
+		{RangeExpression{From: "1 GB"}, "size>=1 GB"},
+		{RangeExpression{To: "now-30d", ToExclusive: true}, "size<now-30d"},
+		{RangeExpression{From: "a", To: "b"}, "size=a..b"},
+		{RangeExpression{From: "a", To: "a"}, "size=a"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected, func(t *testing.T) {
+			if summary := c.r.Summary("size"); summary != c.expected {
+				t.Errorf("Got %q but expected %q", summary, c.expected)
+			}
+		})
+	}
+}
+
+func TestRangeExpressionArg(t *testing.T) {
+	args := map[string]interface{}{"size": ">= 1GB", "other": true}
+	r, rest, err := RangeExpressionArg(args, "size")
+	if err != nil {
+		t.Fatalf("RangeExpressionArg failed with error: %q", err)
+	}
+	if r == nil || r.From != "1GB" {
+		t.Errorf("Got range %+v", r)
+	}
+	if _, ok := rest["size"]; ok || rest["other"] != true {
+		t.Errorf("Got remaining args %+v", rest)
+	}
+	if _, ok := args["size"]; !ok {
+		t.Error("RangeExpressionArg modified the args it was passed")
+	}
+
+	if r, rest, err = RangeExpressionArg(map[string]interface{}{"from": "1"}, "size"); err != nil || r != nil || rest["from"] != "1" {
+		t.Errorf("Got %+v, %+v, %v without a range", r, rest, err)
+	}
+	if _, _, err = RangeExpressionArg(map[string]interface{}{"size": 5}, "size"); err == nil {
+		t.Error("RangeExpressionArg accepted a non-string range")
+	}
+}
@@ -0,0 +1,370 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/olivere/elastic/v7"
+)
+
+// RegexpOptions bounds the regular expressions clauses will send to Elasticsearch. Zero values use the defaults.
+type RegexpOptions struct {
+	// MaxDeterminizedStates is sent with every regexp query, limiting the automaton Elasticsearch may build. Defaults to 10000.
+	MaxDeterminizedStates int
+	// MaxLength is the longest pattern accepted. Defaults to 1000.
+	MaxLength int
+	// MaxRepeat is the largest count accepted in a {n,m} repetition. Defaults to 100.
+	MaxRepeat int
+	// MaxUnboundedRepeats is the most *, +, or {n,} repetitions accepted in one pattern. Defaults to 4.
+	MaxUnboundedRepeats int
+}
+
+type regexpOptionsKey struct{}
+
+// WithRegexpOptions returns a copy of ctx carrying the given RegexpOptions
+func WithRegexpOptions(ctx context.Context, options RegexpOptions) context.Context {
+	return context.WithValue(ctx, regexpOptionsKey{}, options)
+}
+
+// RegexpOptionsFromContext returns the RegexpOptions carried by ctx, if any
+func RegexpOptionsFromContext(ctx context.Context) (RegexpOptions, bool) {
+	options, ok := ctx.Value(regexpOptionsKey{}).(RegexpOptions)
+	return options, ok
+}
+
+// regexpOptions returns the RegexpOptions carried by ctx, with defaults filled in
+func regexpOptions(ctx context.Context) RegexpOptions {
+	options, _ := RegexpOptionsFromContext(ctx)
+	if options.MaxDeterminizedStates <= 0 {
+		options.MaxDeterminizedStates = 10000
+	}
+	if options.MaxLength <= 0 {
+		options.MaxLength = 1000
+	}
+	if options.MaxRepeat <= 0 {
+		options.MaxRepeat = 100
+	}
+	if options.MaxUnboundedRepeats <= 0 {
+		options.MaxUnboundedRepeats = 4
+	}
+	return options
+}
+
+// shorthand character classes, which Lucene does not support, written out in full
+var regexpShorthandClasses = map[byte]string{
+	'd': "0-9",
+	'w': "a-zA-Z0-9_",
+	's': " \t\n\r\f\v",
+}
+
+// regexpControlEscapes maps the Perl-style escapes for control characters to the characters themselves, which Lucene
+// has no escapes for (in Lucene, \t is just a t)
+var regexpControlEscapes = map[byte]string{
+	'a': "\a",
+	'f': "\f",
+	't': "\t",
+	'n': "\n",
+	'r': "\r",
+	'v': "\v",
+}
+
+// writeLuceneLiteral writes r to b so that Lucene matches it literally, escaping it if it is ASCII punctuation
+func writeLuceneLiteral(b *strings.Builder, r rune) {
+	if r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r)) {
+		b.WriteByte('\\')
+	}
+	b.WriteRune(r)
+}
+
+// parseHexEscape parses the rest of a \x escape, either two hex digits or hex digits in braces, at the start of s. It
+// returns the character and how many bytes of s it used.
+func parseHexEscape(s string) (rune, int, error) {
+	digits, used := s, 2
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return 0, 0, errors.New("Unterminated \\x{...} escape")
+		}
+		digits, used = s[1:end], end+1
+	} else if len(s) >= 2 {
+		digits = s[:2]
+	}
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || code > unicode.MaxRune {
+		return 0, 0, fmt.Errorf("Invalid \\x escape \\x%s", digits)
+	}
+	return rune(code), used, nil
+}
+
+// characters with special meaning to Lucene's optional regexp operators (and its string literals), but not to Perl-style regexps
+const luceneOnlySpecialChars = `@&~<>#"`
+
+// POSIX character classes, which Lucene does not support, written out in full
+var regexpPOSIXClasses = map[string]string{
+	"alnum":  "a-zA-Z0-9",
+	"alpha":  "a-zA-Z",
+	"blank":  " \t",
+	"digit":  "0-9",
+	"lower":  "a-z",
+	"space":  " \t\n\r\f\v",
+	"upper":  "A-Z",
+	"word":   "a-zA-Z0-9_",
+	"xdigit": "0-9A-Fa-f",
+}
+
+// hasTopLevelAlternation returns whether a pattern has a | outside of any group or character class
+func hasTopLevelAlternation(pattern string) bool {
+	depth := 0
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass && strings.HasPrefix(pattern[i:], "[:"):
+			if end := strings.Index(pattern[i:], ":]"); end >= 0 {
+				i += end + 1
+			}
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			// a leading ] or ^] is part of the class
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				i++
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			return true
+		}
+	}
+	return false
+}
+
+// convertToLucene rewrites a Perl-style pattern already accepted by regexp/syntax into Lucene's regexp syntax.
+// Lucene patterns always match the entire string, so ends without an anchor are extended with .* and anchors are removed.
+// Shorthand and POSIX classes are expanded, escapes for control and hex characters become the characters themselves,
+// non-capturing groups become plain groups, and characters special only to Lucene are escaped.
+func convertToLucene(pattern string) (string, error) {
+	anchoredStart := strings.HasPrefix(pattern, "^")
+	anchoredEnd := strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, `\$`)
+	pattern = strings.TrimPrefix(pattern, "^")
+	if anchoredEnd {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	body, err := convertBodyToLucene(pattern)
+	if err != nil {
+		return "", err
+	}
+	if hasTopLevelAlternation(pattern) {
+		if anchoredStart || anchoredEnd {
+			return "", errors.New("Anchors cannot be applied to only some alternatives; group them, as in ^(a|b)$")
+		}
+		body = "(" + body + ")"
+	}
+	if !anchoredStart && !strings.HasPrefix(body, ".*") {
+		body = ".*" + body
+	}
+	if !anchoredEnd && (!strings.HasSuffix(body, ".*") || strings.HasSuffix(body, `\.*`)) {
+		body += ".*"
+	}
+	return body, nil
+}
+
+// convertBodyToLucene rewrites a Perl-style pattern with its end anchors removed into Lucene's regexp syntax
+func convertBodyToLucene(pattern string) (string, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			next := pattern[i+1]
+			i++
+			lower := next | 0x20
+			if expanded, ok := regexpShorthandClasses[lower]; ok {
+				negated := next != lower
+				switch {
+				case inClass && negated:
+					return "", fmt.Errorf("Negated shorthand class \\%c cannot be used inside a character class", next)
+				case inClass:
+					b.WriteString(expanded)
+				case negated:
+					b.WriteString("[^" + expanded + "]")
+				default:
+					b.WriteString("[" + expanded + "]")
+				}
+				continue
+			}
+			if control, ok := regexpControlEscapes[next]; ok {
+				b.WriteString(control)
+				continue
+			}
+			if next == 'x' {
+				r, used, err := parseHexEscape(pattern[i+1:])
+				if err != nil {
+					return "", err
+				}
+				writeLuceneLiteral(&b, r)
+				i += used
+				continue
+			}
+			if strings.IndexByte("bBAzZpPQE", next) >= 0 || (next >= '0' && next <= '9') {
+				return "", fmt.Errorf("The escape \\%c is not supported in Elasticsearch regular expressions", next)
+			}
+			b.WriteByte('\\')
+			b.WriteByte(next)
+		case inClass && c == '[' && strings.HasPrefix(pattern[i:], "[:"):
+			end := strings.Index(pattern[i:], ":]")
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			name := pattern[i+2 : i+end]
+			expanded, ok := regexpPOSIXClasses[name]
+			if !ok {
+				return "", fmt.Errorf("The character class [:%s:] is not supported in Elasticsearch regular expressions", name)
+			}
+			b.WriteString(expanded)
+			i += end + 1
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			b.WriteByte(c)
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+			// a leading ] or ^] is part of the class
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				b.WriteByte('^')
+				i++
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				b.WriteString(`\]`)
+				i++
+			}
+		case c == '(' && strings.HasPrefix(pattern[i:], "(?:"):
+			b.WriteByte('(')
+			i += 2
+		case c == '(' && i+1 < len(pattern) && pattern[i+1] == '?':
+			return "", errors.New("Flags and lookaround groups are not supported in Elasticsearch regular expressions")
+		case c == '^' || c == '$':
+			return "", fmt.Errorf("The anchor %c may only appear at the start or end of a pattern", c)
+		case (c == '*' || c == '+' || c == '?' || c == '}') && i+1 < len(pattern) && pattern[i+1] == '?':
+			return "", errors.New("Lazy quantifiers are not supported in Elasticsearch regular expressions")
+		case strings.IndexByte(luceneOnlySpecialChars, c) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// checkRegexpComplexity rejects patterns with nested unbounded repetition, very large repetition counts, or too many unbounded repetitions
+func checkRegexpComplexity(re *syntax.Regexp, options RegexpOptions) error {
+	var unbounded int
+	var walk func(re *syntax.Regexp, insideUnbounded bool) error
+	walk = func(re *syntax.Regexp, insideUnbounded bool) error {
+		isUnbounded := re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max == -1)
+		if isUnbounded {
+			if insideUnbounded {
+				return errors.New("Nested unbounded repetition, such as (a+)+, is too slow to search")
+			}
+			unbounded++
+			if unbounded > options.MaxUnboundedRepeats {
+				return fmt.Errorf("Patterns may contain at most %d unbounded repetitions", options.MaxUnboundedRepeats)
+			}
+		}
+		if re.Op == syntax.OpRepeat && (re.Max > options.MaxRepeat || re.Min > options.MaxRepeat) {
+			return fmt.Errorf("Repetition counts may be at most %d", options.MaxRepeat)
+		}
+		for _, sub := range re.Sub {
+			if err := walk(sub, insideUnbounded || isUnbounded); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(re, false)
+}
+
+// NormalizeRegexp validates a Perl-style regular expression, such as ^SRR\d+_[12]\.fastq\.gz$, and converts it into
+// Lucene regexp syntax for Elasticsearch. Patterns using syntax Lucene does not support, or likely to be catastrophically
+// slow according to the RegexpOptions carried by ctx, are rejected.
+func NormalizeRegexp(ctx context.Context, pattern string) (string, error) {
+	options := regexpOptions(ctx)
+
+	if pattern == "" {
+		return "", errors.New("No regular expression was passed")
+	}
+	if len(pattern) > options.MaxLength {
+		return "", fmt.Errorf("Regular expressions may be at most %d characters long", options.MaxLength)
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("Invalid regular expression %q: %w", pattern, err)
+	}
+	if err = checkRegexpComplexity(parsed, options); err != nil {
+		return "", err
+	}
+
+	return convertToLucene(pattern)
+}
+
+// RegexpQuery validates and normalizes a pattern with NormalizeRegexp, and creates a regexp query for it on field
+func RegexpQuery(ctx context.Context, field, pattern string) (elastic.Query, error) {
+	normalized, err := NormalizeRegexp(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return LuceneRegexpQuery(ctx, field, normalized), nil
+}
+
+// LuceneRegexpQuery creates a regexp query on field for a pattern already in Lucene regexp syntax, such as one built by
+// clause code, limited by the RegexpOptions carried by ctx. User input should go through RegexpQuery instead.
+func LuceneRegexpQuery(ctx context.Context, field, pattern string) *elastic.RegexpQuery {
+	return elastic.NewRegexpQuery(field, pattern).MaxDeterminizedStates(regexpOptions(ctx).MaxDeterminizedStates)
+}
+
+// characters with special meaning anywhere in a Lucene regexp
+const luceneRegexpReserved = `.?+*|{}[]()"\#@&<>~`
+
+// EscapeRegexp escapes every character in s with special meaning in Lucene regexp syntax, so it matches literally
+func EscapeRegexp(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(luceneRegexpReserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// RegexpCost estimates the cost of a regexp query for a pattern, treating patterns which are unanchored or start with an
+// unbounded wildcard as leading wildcards
+func RegexpCost(pattern string) clause.ClauseCost {
+	if !strings.HasPrefix(pattern, "^") || strings.HasPrefix(pattern, "^.*") || strings.HasPrefix(pattern, "^.+") {
+		return clause.ClauseCost{Cost: LeadingWildcardCost, Terms: 1, LeadingWildcards: 1}
+	}
+	return clause.ClauseCost{Cost: WildcardCost, Terms: 1}
+}
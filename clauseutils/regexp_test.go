@@ -0,0 +1,138 @@
+package clauseutils
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestNormalizeRegexp(t *testing.T) {
+	cases := []struct {
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{input: `^SRR\d+_[12]\.fastq\.gz$`, expected: `SRR[0-9]+_[12]\.fastq\.gz`},
+		{input: `^foo\$$`, expected: `foo\$`},
+		{input: `^[\w.-]+\s\D$`, expected: "[a-zA-Z0-9_.-]+[ \t\n\r\f\v][^0-9]"},
+		{input: `^a\tb\nc[\r\f]$`, expected: "a\tb\nc[\r\f]"},
+		{input: `^\x41\x2e\x{2603}$`, expected: `A\.☃`},
+		{input: `\x4`, shouldErr: true},        // short hex escape, rejected by regexp/syntax
+		{input: `\x{110000}`, shouldErr: true}, // beyond unicode
+		{input: `^(?:ab|cd)+$`, expected: `(ab|cd)+`},
+		{input: `^[]a]x$`, expected: `[\]a]x`},
+		{input: `^a@b&c~d<e>f#g"h$`, expected: `a\@b\&c\~d\<e\>f\#g\"h`},
+		{input: `^[@#]$`, expected: `[@#]`},
+		{input: `^a{2,5}$`, expected: `a{2,5}`},
+		{input: `^[[:alpha:][:digit:]_]+$`, expected: `[a-zA-Z0-9_]+`},
+		{input: `^[^[:space:]]$`, expected: "[^ \t\n\r\f\v]"},
+		{input: `foo`, expected: `.*foo.*`},
+		{input: `^foo`, expected: `foo.*`},
+		{input: `foo$`, expected: `.*foo`},
+		{input: `.*foo.*`, expected: `.*foo.*`},
+		{input: `foo\.*`, expected: `.*foo\.*.*`},
+		{input: `foo|bar`, expected: `.*(foo|bar).*`},
+		{input: `^[[:digit:]|]$`, expected: `[0-9|]`},
+		{input: `^(foo|bar)$`, expected: `(foo|bar)`},
+		{input: `^foo|bar$`, shouldErr: true},      // anchors on only some alternatives
+		{input: `^[[:punct:]]$`, shouldErr: true},  // unsupported POSIX class
+		{input: `^[[:^alpha:]]$`, shouldErr: true}, // negated POSIX class
+		{input: "", shouldErr: true},
+		{input: "foo(", shouldErr: true},            // unbalanced
+		{input: "[a-", shouldErr: true},             // unterminated class
+		{input: "(?i)foo", shouldErr: true},         // flags
+		{input: "foo(?!bar)", shouldErr: true},      // lookaround, rejected by regexp/syntax
+		{input: `(a)\1`, shouldErr: true},           // backreference
+		{input: `\bfoo`, shouldErr: true},           // word boundary
+		{input: "a.*?b", shouldErr: true},           // lazy quantifier
+		{input: "a^b", shouldErr: true},             // anchor in the middle
+		{input: `[^\D]`, shouldErr: true},           // negated shorthand in a class
+		{input: "(a+)+", shouldErr: true},           // nested repetition
+		{input: "(a*b?)*c", shouldErr: true},        // nested repetition
+		{input: "(ab{2,})+", shouldErr: true},       // nested repetition
+		{input: "a{1,1000}", shouldErr: true},       // huge repetition
+		{input: ".*a.*b.*c.*d.*e", shouldErr: true}, // too many unbounded repetitions
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			normalized, err := NormalizeRegexp(context.Background(), c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("NormalizeRegexp should have failed, instead returned %q", normalized)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("NormalizeRegexp failed with error: %q", err)
+			} else if !c.shouldErr && normalized != c.expected {
+				t.Errorf("Got %q but expected %q", normalized, c.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeRegexpWhitespace(t *testing.T) {
+	normalized, err := NormalizeRegexp(context.Background(), `foo\sbar`)
+	if err != nil {
+		t.Fatalf("NormalizeRegexp failed with error: %q", err)
+	}
+	// Lucene and Go agree on character classes of literal characters, so Go can check what the pattern matches
+	re := regexp.MustCompile("^" + normalized + "$")
+	for _, s := range []string{"foo bar", "foo\tbar", "foo\nbar"} {
+		if !re.MatchString(s) {
+			t.Errorf("%q (from %q) did not match %q", normalized, `foo\sbar`, s)
+		}
+	}
+	for _, s := range []string{"footbar", "foonbar", "foorbar"} {
+		if re.MatchString(s) {
+			t.Errorf("%q (from %q) matched %q", normalized, `foo\sbar`, s)
+		}
+	}
+}
+
+func TestNormalizeRegexpOptions(t *testing.T) {
+	ctx := WithRegexpOptions(context.Background(), RegexpOptions{MaxLength: 10, MaxRepeat: 500})
+
+	if _, err := NormalizeRegexp(ctx, "abcdefghijk"); err == nil {
+		t.Error("NormalizeRegexp accepted a pattern longer than MaxLength")
+	}
+	if _, err := NormalizeRegexp(ctx, "a{1,200}"); err != nil {
+		t.Errorf("NormalizeRegexp rejected a repetition under MaxRepeat: %q", err)
+	}
+}
+
+func TestRegexpQuery(t *testing.T) {
+	ctx := WithRegexpOptions(context.Background(), RegexpOptions{MaxDeterminizedStates: 500})
+	query, err := RegexpQuery(ctx, "label.keyword", "^foo.*$")
+	if err != nil {
+		t.Fatalf("RegexpQuery failed with error: %q", err)
+	}
+	source, err := query.Source()
+	if err != nil {
+		t.Fatalf("Source get failed with error: %q", err)
+	}
+	field := source.(map[string]interface{})["regexp"].(map[string]interface{})["label.keyword"].(map[string]interface{})
+	if field["value"] != "foo.*" {
+		t.Errorf("Got pattern %v, expected foo.*", field["value"])
+	}
+	if field["max_determinized_states"] != 500 {
+		t.Errorf("Got max_determinized_states %v, expected 500", field["max_determinized_states"])
+	}
+}
+
+func TestRegexpCost(t *testing.T) {
+	if cost := RegexpCost("^foo.*"); cost.LeadingWildcards != 0 || cost.Cost != WildcardCost {
+		t.Errorf("Got %+v for an anchored pattern", cost)
+	}
+	if cost := RegexpCost(".*foo"); cost.LeadingWildcards != 1 || cost.Cost != LeadingWildcardCost {
+		t.Errorf("Got %+v for a pattern with a leading wildcard", cost)
+	}
+	if cost := RegexpCost("foo"); cost.LeadingWildcards != 1 || cost.Cost != LeadingWildcardCost {
+		t.Errorf("Got %+v for an unanchored pattern", cost)
+	}
+}
+
+func TestEscapeRegexp(t *testing.T) {
+	input := `/a.b?c+d*e|f{g}h[i]j(k)l"m\n#o@p&q<r>s~t`
+	expected := `/a\.b\?c\+d\*e\|f\{g\}h\[i\]j\(k\)l\"m\\n\#o\@p\&q\<r\>s\~t`
+	if escaped := EscapeRegexp(input); escaped != expected {
+		t.Errorf("Got %q but expected %q", escaped, expected)
+	}
+}
@@ -0,0 +1,94 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// DefaultTagTargetsPath is the field of tag documents listing the IDs of the objects they're attached to
+const DefaultTagTargetsPath = "targets.id"
+
+// TagResolver looks up the IDs of the tags with a name. If owner is blank, tags owned by anyone match.
+type TagResolver interface {
+	ResolveTags(ctx context.Context, name, owner string) ([]string, error)
+}
+
+// TagResolverFunc adapts a function into a TagResolver
+type TagResolverFunc func(ctx context.Context, name, owner string) ([]string, error)
+
+// ResolveTags implements TagResolver
+func (f TagResolverFunc) ResolveTags(ctx context.Context, name, owner string) ([]string, error) {
+	return f(ctx, name, owner)
+}
+
+// TagLookupOptions says where the terms lookups for tags find tag documents
+type TagLookupOptions struct {
+	// Index is the index holding tag documents. If blank, Elasticsearch's default is used.
+	Index string
+	// Type is the document type of tags, for clusters which still use types
+	Type string
+	// Path is the field of tag documents listing the IDs of the objects they're attached to. Defaults to DefaultTagTargetsPath.
+	Path string
+}
+
+// TermsLookup returns the terms lookup for the objects the tag with the given ID is attached to
+func (o TagLookupOptions) TermsLookup(id string) *elastic.TermsLookup {
+	path := o.Path
+	if path == "" {
+		path = DefaultTagTargetsPath
+	}
+	lookup := elastic.NewTermsLookup().Id(id).Path(path)
+	if o.Index != "" {
+		lookup.Index(o.Index)
+	}
+	if o.Type != "" {
+		lookup.Type(o.Type)
+	}
+	return lookup
+}
+
+type tagResolverKey struct{}
+type tagLookupOptionsKey struct{}
+
+// WithTagResolver returns a copy of ctx carrying the given TagResolver
+func WithTagResolver(ctx context.Context, resolver TagResolver) context.Context {
+	return context.WithValue(ctx, tagResolverKey{}, resolver)
+}
+
+// TagResolverFromContext returns the TagResolver carried by ctx, if any
+func TagResolverFromContext(ctx context.Context) (TagResolver, bool) {
+	resolver, ok := ctx.Value(tagResolverKey{}).(TagResolver)
+	return resolver, ok
+}
+
+// WithTagLookupOptions returns a copy of ctx carrying the given TagLookupOptions
+func WithTagLookupOptions(ctx context.Context, options TagLookupOptions) context.Context {
+	return context.WithValue(ctx, tagLookupOptionsKey{}, options)
+}
+
+// TagLookupOptionsFromContext returns the TagLookupOptions carried by ctx, if any
+func TagLookupOptionsFromContext(ctx context.Context) (TagLookupOptions, bool) {
+	options, ok := ctx.Value(tagLookupOptionsKey{}).(TagLookupOptions)
+	return options, ok
+}
+
+// ResolveTags looks up the IDs of the tags with a name using the TagResolver carried by ctx. It fails if there is no
+// resolver or no tags have the name.
+func ResolveTags(ctx context.Context, name, owner string) ([]string, error) {
+	resolver, ok := TagResolverFromContext(ctx)
+	if !ok {
+		return nil, errors.New("No tag resolver is configured, cannot look up tags by name")
+	}
+
+	ids, err := resolver.ResolveTags(ctx, name, owner)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to look up tag %q: %w", name, err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("No tags named %q were found", name)
+	}
+	return ids, nil
+}
@@ -0,0 +1,48 @@
+package clauseutils
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTagLookupOptions(t *testing.T) {
+	cases := []struct {
+		options  TagLookupOptions
+		expected map[string]interface{}
+	}{
+		{TagLookupOptions{}, map[string]interface{}{"id": "t1", "path": "targets.id"}},
+		{TagLookupOptions{Index: "tags", Type: "tag", Path: "targets"}, map[string]interface{}{"id": "t1", "path": "targets", "index": "tags", "type": "tag"}},
+	}
+
+	for _, c := range cases {
+		source, err := c.options.TermsLookup("t1").Source()
+		if err != nil {
+			t.Fatalf("Source failed with error: %q", err)
+		}
+		if !reflect.DeepEqual(source, c.expected) {
+			t.Errorf("Got terms lookup %+v for %+v, expected %+v", source, c.options, c.expected)
+		}
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	resolver := TagResolverFunc(func(_ context.Context, name, owner string) ([]string, error) {
+		if name == "favorites" && owner == "ipctest" {
+			return []string{"t1"}, nil
+		}
+		return nil, nil
+	})
+	ctx := WithTagResolver(context.Background(), resolver)
+
+	ids, err := ResolveTags(ctx, "favorites", "ipctest")
+	if err != nil || !reflect.DeepEqual(ids, []string{"t1"}) {
+		t.Errorf("ResolveTags returned %v and error %v, expected [t1]", ids, err)
+	}
+	if _, err := ResolveTags(ctx, "favorites", "mian"); err == nil {
+		t.Error("ResolveTags should have failed for a name with no tags")
+	}
+	if _, err := ResolveTags(context.Background(), "favorites", "ipctest"); err == nil {
+		t.Error("ResolveTags should have failed without a tag resolver")
+	}
+}
@@ -0,0 +1,136 @@
+package clauseutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The attribute types of CyVerse metadata templates which clauses search differently from free text
+const (
+	TemplateEnum      = "Enum"
+	TemplateNumber    = "Number"
+	TemplateInteger   = "Integer"
+	TemplateDate      = "Date"
+	TemplateTimestamp = "Timestamp"
+	TemplateBoolean   = "Boolean"
+	TemplateURL       = "URL/URI"
+)
+
+// DefaultTemplateNamespace is the metadata namespace holding AVUs filled in from metadata templates when no other is configured
+const DefaultTemplateNamespace = "cyverse"
+
+// ErrTemplateNotFound is returned by a TemplateProvider which has no template with the requested ID
+var ErrTemplateNotFound = errors.New("metadata template not found")
+
+// TemplateAttribute is a single attribute of a metadata template
+type TemplateAttribute struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`
+	Enum []string `json:"values,omitempty"`
+}
+
+// MetadataTemplate describes the attributes of a CyVerse metadata template
+type MetadataTemplate struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	Attributes []TemplateAttribute `json:"attributes"`
+}
+
+// Attribute returns the template's attribute with the given name, if it has one
+func (t *MetadataTemplate) Attribute(name string) (*TemplateAttribute, bool) {
+	for i := range t.Attributes {
+		if t.Attributes[i].Name == name {
+			return &t.Attributes[i], true
+		}
+	}
+	return nil, false
+}
+
+// TemplateProvider looks up metadata templates by ID
+type TemplateProvider interface {
+	Template(ctx context.Context, id string) (*MetadataTemplate, error)
+}
+
+// JSONTemplateProvider is a TemplateProvider serving a fixed set of templates read from JSON
+type JSONTemplateProvider struct {
+	templates map[string]*MetadataTemplate
+}
+
+// NewJSONTemplateProvider creates a JSONTemplateProvider from a JSON array of templates
+func NewJSONTemplateProvider(r io.Reader) (*JSONTemplateProvider, error) {
+	var templates []*MetadataTemplate
+	if err := json.NewDecoder(r).Decode(&templates); err != nil {
+		return nil, fmt.Errorf("Could not read metadata templates: %w", err)
+	}
+
+	provider := &JSONTemplateProvider{templates: make(map[string]*MetadataTemplate)}
+	for _, t := range templates {
+		if t.ID == "" {
+			return nil, errors.New("Metadata templates must have an ID.")
+		}
+		provider.templates[t.ID] = t
+	}
+	return provider, nil
+}
+
+// LoadJSONTemplateProvider creates a JSONTemplateProvider from a file holding a JSON array of templates
+func LoadJSONTemplateProvider(path string) (*JSONTemplateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewJSONTemplateProvider(f)
+}
+
+// Template implements TemplateProvider
+func (p *JSONTemplateProvider) Template(_ context.Context, id string) (*MetadataTemplate, error) {
+	t, ok := p.templates[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, id)
+	}
+	return t, nil
+}
+
+type templateProviderKey struct{}
+type templateNamespaceKey struct{}
+
+// WithTemplateProvider returns a copy of ctx carrying the given TemplateProvider
+func WithTemplateProvider(ctx context.Context, provider TemplateProvider) context.Context {
+	return context.WithValue(ctx, templateProviderKey{}, provider)
+}
+
+// TemplateProviderFromContext returns the TemplateProvider carried by ctx, if any
+func TemplateProviderFromContext(ctx context.Context) (TemplateProvider, bool) {
+	provider, ok := ctx.Value(templateProviderKey{}).(TemplateProvider)
+	return provider, ok
+}
+
+// WithTemplateNamespace returns a copy of ctx carrying the name of the metadata namespace holding AVUs filled in from templates
+func WithTemplateNamespace(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, templateNamespaceKey{}, name)
+}
+
+// TemplateNamespaceFromContext returns the name of the template metadata namespace carried by ctx, if any
+func TemplateNamespaceFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(templateNamespaceKey{}).(string)
+	return name, ok
+}
+
+// TemplateNamespace returns the metadata namespace holding AVUs filled in from templates: the one named in ctx, or
+// DefaultTemplateNamespace, looked up in the metadata namespaces carried by ctx. It fails if there is no such namespace.
+func TemplateNamespace(ctx context.Context) (MetadataNamespace, error) {
+	name, ok := TemplateNamespaceFromContext(ctx)
+	if !ok {
+		name = DefaultTemplateNamespace
+	}
+	ns, ok := MetadataNamespacesOrDefault(ctx).Lookup(name)
+	if !ok {
+		return MetadataNamespace{}, fmt.Errorf("The metadata template namespace %q is not a configured metadata namespace", name)
+	}
+	return ns, nil
+}
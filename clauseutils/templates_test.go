@@ -0,0 +1,42 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONTemplateProvider(t *testing.T) {
+	provider, err := NewJSONTemplateProvider(strings.NewReader(`[{"id": "t1", "name": "Test", "attributes": [{"name": "tissue", "type": "Enum", "values": ["liver", "heart"]}]}]`))
+	if err != nil {
+		t.Fatalf("NewJSONTemplateProvider failed with error: %q", err)
+	}
+
+	template, err := provider.Template(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Template failed with error: %q", err)
+	}
+	attr, ok := template.Attribute("tissue")
+	if !ok {
+		t.Fatalf("Template %+v is missing the tissue attribute", template)
+	}
+	if attr.Type != TemplateEnum || len(attr.Enum) != 2 {
+		t.Errorf("Got attribute %+v, expected an Enum with two values", attr)
+	}
+	if _, ok := template.Attribute("species"); ok {
+		t.Errorf("Template %+v should not have a species attribute", template)
+	}
+
+	if _, err := provider.Template(context.Background(), "t2"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Template returned %v rather than an ErrTemplateNotFound", err)
+	}
+}
+
+func TestJSONTemplateProviderInvalid(t *testing.T) {
+	for _, input := range []string{`{"id": "t1"}`, `[{"name": "No ID"}]`, `not json`} {
+		if _, err := NewJSONTemplateProvider(strings.NewReader(input)); err == nil {
+			t.Errorf("NewJSONTemplateProvider should have failed for %s", input)
+		}
+	}
+}
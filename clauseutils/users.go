@@ -0,0 +1,159 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UsernameDelimiter separates a username from its iRODS zone in a qualified username
+const UsernameDelimiter = "#"
+
+// ResolvedUser describes how a single username should be matched: against a set of exact qualified usernames, a wildcard pattern, or both.
+type ResolvedUser struct {
+	Terms    []string
+	Wildcard string
+}
+
+// UserResolver expands a possibly-unqualified username into the qualified usernames (or pattern) it should match
+type UserResolver interface {
+	ResolveUser(ctx context.Context, username string) (ResolvedUser, error)
+}
+
+// IsQualifiedUsername checks if a username already contains the zone delimiter
+func IsQualifiedUsername(username, delimiter string) bool {
+	if delimiter == "" {
+		delimiter = UsernameDelimiter
+	}
+	return strings.Contains(username, delimiter)
+}
+
+// WildcardUserResolver is the default UserResolver. It matches unqualified usernames against any zone with a wildcard, and qualified usernames exactly.
+type WildcardUserResolver struct {
+	// Delimiter separates usernames and zones. Defaults to UsernameDelimiter.
+	Delimiter string
+}
+
+// ResolveUser implements UserResolver
+func (r WildcardUserResolver) ResolveUser(_ context.Context, username string) (ResolvedUser, error) {
+	delimiter := r.Delimiter
+	if delimiter == "" {
+		delimiter = UsernameDelimiter
+	}
+	if IsQualifiedUsername(username, delimiter) {
+		return ResolvedUser{Terms: []string{username}}, nil
+	}
+	return ResolvedUser{Wildcard: username + delimiter + "*"}, nil
+}
+
+// ZoneUserResolver qualifies unqualified usernames with each of a configured list of zones, so they can be matched exactly
+type ZoneUserResolver struct {
+	Zones []string
+	// Delimiter separates usernames and zones. Defaults to UsernameDelimiter.
+	Delimiter string
+}
+
+// ResolveUser implements UserResolver
+func (r ZoneUserResolver) ResolveUser(_ context.Context, username string) (ResolvedUser, error) {
+	delimiter := r.Delimiter
+	if delimiter == "" {
+		delimiter = UsernameDelimiter
+	}
+	if IsQualifiedUsername(username, delimiter) {
+		return ResolvedUser{Terms: []string{username}}, nil
+	}
+	terms := make([]string, len(r.Zones))
+	for i, zone := range r.Zones {
+		terms[i] = username + delimiter + zone
+	}
+	return ResolvedUser{Terms: terms}, nil
+}
+
+// UserLookupFunc adapts a function returning the qualified usernames for a username into a UserResolver
+type UserLookupFunc func(ctx context.Context, username string) ([]string, error)
+
+// ResolveUser implements UserResolver
+func (f UserLookupFunc) ResolveUser(ctx context.Context, username string) (ResolvedUser, error) {
+	terms, err := f(ctx, username)
+	if err != nil {
+		return ResolvedUser{}, err
+	}
+	return ResolvedUser{Terms: terms}, nil
+}
+
+type userResolverKey struct{}
+type currentUserKey struct{}
+
+// WithCurrentUser returns a copy of ctx carrying the username of the user performing a search
+func WithCurrentUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, currentUserKey{}, username)
+}
+
+// CurrentUserFromContext returns the username of the user performing a search, if ctx carries one
+func CurrentUserFromContext(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(currentUserKey{}).(string)
+	return username, ok && username != ""
+}
+
+// WithUserResolver returns a copy of ctx carrying the given UserResolver
+func WithUserResolver(ctx context.Context, resolver UserResolver) context.Context {
+	return context.WithValue(ctx, userResolverKey{}, resolver)
+}
+
+// UserResolverFromContext returns the UserResolver carried by ctx, if any
+func UserResolverFromContext(ctx context.Context) (UserResolver, bool) {
+	resolver, ok := ctx.Value(userResolverKey{}).(UserResolver)
+	return resolver, ok
+}
+
+// ResolveUser resolves a username with the UserResolver carried by ctx, falling back to a WildcardUserResolver
+func ResolveUser(ctx context.Context, username string) (ResolvedUser, error) {
+	resolver, ok := UserResolverFromContext(ctx)
+	if !ok {
+		resolver = WildcardUserResolver{}
+	}
+	return resolver.ResolveUser(ctx, username)
+}
+
+// UserOrCurrent returns username if it is set, otherwise the current user carried by ctx
+func UserOrCurrent(ctx context.Context, username string) (string, error) {
+	if username != "" {
+		return username, nil
+	}
+	if current, ok := CurrentUserFromContext(ctx); ok {
+		return current, nil
+	}
+	return "", errors.New("No user was passed and no current user is available")
+}
+
+// ExactUsernames resolves a username to the exact qualified usernames it refers to, for uses such as security filters
+// where a wildcard would widen access. Usernames containing wildcard or escape characters are rejected. Without a
+// UserResolver in ctx, the username must already be qualified; with one, it must resolve to exact terms only.
+func ExactUsernames(ctx context.Context, username string) ([]string, error) {
+	if username == "" || strings.ContainsAny(username, `*?\`) {
+		return nil, fmt.Errorf("The username %q is not a valid exact username", username)
+	}
+
+	resolver, ok := UserResolverFromContext(ctx)
+	if !ok {
+		if !IsQualifiedUsername(username, "") {
+			return nil, fmt.Errorf("The username %q is not qualified with a zone, and no user resolver is configured", username)
+		}
+		return []string{username}, nil
+	}
+
+	resolved, err := resolver.ResolveUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Wildcard != "" || len(resolved.Terms) == 0 {
+		return nil, fmt.Errorf("The username %q did not resolve to exact usernames", username)
+	}
+	for _, term := range resolved.Terms {
+		if strings.ContainsAny(term, `*?\`) {
+			return nil, fmt.Errorf("The username %q resolved to %q, which is not an exact username", username, term)
+		}
+	}
+	return resolved.Terms, nil
+}
@@ -0,0 +1,51 @@
+package clauseutils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveUser(t *testing.T) {
+	lookup := UserLookupFunc(func(_ context.Context, username string) ([]string, error) {
+		if username == "missing" {
+			return nil, errors.New("no such user")
+		}
+		return []string{username + "#looked-up"}, nil
+	})
+
+	cases := []struct {
+		name      string
+		resolver  UserResolver
+		input     string
+		expected  ResolvedUser
+		shouldErr bool
+	}{
+		{name: "default", input: "foo", expected: ResolvedUser{Wildcard: "foo#*"}},
+		{name: "default-qualified", input: "foo#iplant", expected: ResolvedUser{Terms: []string{"foo#iplant"}}},
+		{name: "wildcard-delimiter", resolver: WildcardUserResolver{Delimiter: "@"}, input: "foo", expected: ResolvedUser{Wildcard: "foo@*"}},
+		{name: "zones", resolver: ZoneUserResolver{Zones: []string{"iplant", "cyverse"}}, input: "foo", expected: ResolvedUser{Terms: []string{"foo#iplant", "foo#cyverse"}}},
+		{name: "zones-qualified", resolver: ZoneUserResolver{Zones: []string{"iplant"}}, input: "foo#other", expected: ResolvedUser{Terms: []string{"foo#other"}}},
+		{name: "lookup", resolver: lookup, input: "foo", expected: ResolvedUser{Terms: []string{"foo#looked-up"}}},
+		{name: "lookup-error", resolver: lookup, input: "missing", shouldErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.resolver != nil {
+				ctx = WithUserResolver(ctx, c.resolver)
+			}
+
+			resolved, err := ResolveUser(ctx, c.input)
+			if c.shouldErr && err == nil {
+				t.Errorf("ResolveUser should have failed, instead returned %+v", resolved)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("ResolveUser failed with error: %q", err)
+			} else if !c.shouldErr && !reflect.DeepEqual(resolved, c.expected) {
+				t.Errorf("ResolveUser returned %+v instead of expected %+v", resolved, c.expected)
+			}
+		})
+	}
+}
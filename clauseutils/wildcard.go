@@ -0,0 +1,197 @@
+package clauseutils
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/olivere/elastic/v7"
+)
+
+// WildcardStrategy determines how inexact text searches are matched
+type WildcardStrategy string
+
+const (
+	// ContainsWildcard wraps each term as *term* in a query_string query. This is the default, and the most expensive on large indices.
+	ContainsWildcard WildcardStrategy = "contains"
+	// PrefixWildcard matches each term as a prefix, term*, and never uses leading wildcards
+	PrefixWildcard WildcardStrategy = "prefix"
+	// NGramMatch matches each term against an n-gram analyzed subfield with a match query requiring all of the term's n-grams
+	NGramMatch WildcardStrategy = "ngram"
+	// WildcardFieldMatch uses wildcard queries on a subfield of Elasticsearch's 'wildcard' field type, which handles leading wildcards efficiently
+	WildcardFieldMatch WildcardStrategy = "wildcard_field"
+)
+
+// WildcardOptions configures how inexact text searches are matched
+type WildcardOptions struct {
+	Strategy WildcardStrategy
+	// NGramSubfield is the subfield used by NGramMatch. Defaults to 'ngram'.
+	NGramSubfield string
+	// WildcardSubfield is the subfield used by WildcardFieldMatch. Defaults to 'wildcard'.
+	WildcardSubfield string
+}
+
+type wildcardOptionsKey struct{}
+
+// WithWildcardOptions returns a copy of ctx carrying the given WildcardOptions
+func WithWildcardOptions(ctx context.Context, options WildcardOptions) context.Context {
+	return context.WithValue(ctx, wildcardOptionsKey{}, options)
+}
+
+// WildcardOptionsFromContext returns the WildcardOptions carried by ctx, if any
+func WildcardOptionsFromContext(ctx context.Context) (WildcardOptions, bool) {
+	options, ok := ctx.Value(wildcardOptionsKey{}).(WildcardOptions)
+	return options, ok
+}
+
+// wildcardOptions returns the WildcardOptions carried by ctx, with defaults filled in
+func wildcardOptions(ctx context.Context) WildcardOptions {
+	options, _ := WildcardOptionsFromContext(ctx)
+	if options.Strategy == "" {
+		options.Strategy = ContainsWildcard
+	}
+	if options.NGramSubfield == "" {
+		options.NGramSubfield = "ngram"
+	}
+	if options.WildcardSubfield == "" {
+		options.WildcardSubfield = "wildcard"
+	}
+	return options
+}
+
+var (
+	hasWildcardSyntax = regexp.MustCompile(`[*?\\"]`)
+	// operators other than the wildcards, quotes, escapes, and OR used by implicit wildcard searches
+	queryStringOperatorChars = regexp.MustCompile(`[+\-=&|><!(){}\[\]^~:/]`)
+	queryStringOperatorWords = regexp.MustCompile(`^(AND|NOT)$`)
+	// every character and word with a special meaning to query_string
+	queryStringReservedChars = regexp.MustCompile(`[+\-=&|><!(){}\[\]^"~*?:\\/]`)
+	queryStringReservedWords = regexp.MustCompile(`^(AND|OR|NOT)$`)
+)
+
+// SanitizeQueryString escapes query_string syntax in input other than wildcards, quoted phrases, backslash escapes, and OR,
+// so that users cannot target other fields, use regular expressions, fuzzy or proximity searches, ranges, or boolean operators.
+func SanitizeQueryString(input string) string {
+	parts := strings.Fields(input)
+	for i, part := range parts {
+		if queryStringOperatorWords.MatchString(part) {
+			parts[i] = `\` + part
+			continue
+		}
+		parts[i] = queryStringOperatorChars.ReplaceAllString(part, `\$0`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// EscapeQueryString escapes all query_string syntax in input, so it is searched for literally: every Lucene reserved character
+// (+ - = & | > < ! ( ) { } [ ] ^ " ~ * ? : \ /) is preceded by a backslash, as are the boolean operators AND, OR, and NOT.
+// Whitespace still separates terms.
+func EscapeQueryString(input string) string {
+	parts := strings.Fields(input)
+	for i, part := range parts {
+		if queryStringReservedWords.MatchString(part) {
+			parts[i] = `\` + part
+			continue
+		}
+		parts[i] = queryStringReservedChars.ReplaceAllString(part, `\$0`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// QuoteQueryString escapes input with EscapeQueryString and quotes it, so a query_string query matches it as a single phrase
+func QuoteQueryString(input string) string {
+	return `"` + EscapeQueryString(input) + `"`
+}
+
+// stripLeadingWildcards removes wildcards at the start of each whitespace-separated part of input
+func stripLeadingWildcards(input string) string {
+	var parts []string
+	for _, part := range strings.Fields(input) {
+		part = strings.TrimLeft(part, "*?")
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// AddSafeImplicitWildcard is AddImplicitWildcard, but sanitizes the input with SanitizeQueryString
+func AddSafeImplicitWildcard(input string) string {
+	if hasWildcardSyntax.MatchString(input) {
+		return SanitizeQueryString(input)
+	}
+	return wrapImplicitWildcards(SanitizeQueryString(input))
+}
+
+// AddImplicitPrefixWildcard sanitizes input with SanitizeQueryString and adds a trailing wildcard to each term.
+// Any leading wildcards are removed. As with AddImplicitWildcard, input which already has wildcard-y syntax is otherwise left alone.
+func AddImplicitPrefixWildcard(input string) string {
+	if hasWildcardSyntax.MatchString(input) {
+		return stripLeadingWildcards(SanitizeQueryString(input))
+	}
+
+	var rejoin []string
+	for _, part := range strings.Fields(SanitizeQueryString(input)) {
+		if part != "OR" {
+			rejoin = append(rejoin, part+"*")
+		}
+	}
+	return strings.Join(rejoin, " ")
+}
+
+// escapeWildcardPattern escapes the characters special to wildcard queries
+func escapeWildcardPattern(input string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(input)
+}
+
+// anyTermQuery creates a query matching any of the whitespace-separated terms of input, each matched by termQuery
+func anyTermQuery(input string, termQuery func(term string) elastic.Query) elastic.Query {
+	var queries []elastic.Query
+	for _, part := range strings.Fields(input) {
+		if part != "OR" {
+			queries = append(queries, termQuery(part))
+		}
+	}
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(queries...)
+}
+
+// WildcardQuery creates a query for an inexact search of field, using the WildcardStrategy carried by ctx or ContainsWildcard.
+// Whatever the strategy, a search for several terms matches text containing any one of them, as query_string does by default.
+func WildcardQuery(ctx context.Context, field, input string) elastic.Query {
+	options := wildcardOptions(ctx)
+	switch options.Strategy {
+	case PrefixWildcard:
+		return elastic.NewQueryStringQuery(AddImplicitPrefixWildcard(input)).Field(field)
+	case NGramMatch:
+		return anyTermQuery(input, func(term string) elastic.Query {
+			return elastic.NewMatchQuery(field+"."+options.NGramSubfield, term).Operator("and")
+		})
+	case WildcardFieldMatch:
+		return anyTermQuery(input, func(term string) elastic.Query {
+			return elastic.NewWildcardQuery(field+"."+options.WildcardSubfield, "*"+escapeWildcardPattern(term)+"*")
+		})
+	default:
+		return elastic.NewQueryStringQuery(AddSafeImplicitWildcard(input)).Field(field)
+	}
+}
+
+// WildcardSearchCost estimates the cost of a WildcardQuery for input, using the WildcardStrategy carried by ctx, or of a plain query_string query if exact is set
+func WildcardSearchCost(ctx context.Context, input string, exact bool) clause.ClauseCost {
+	if exact {
+		return QueryStringCost(input, true)
+	}
+
+	terms := len(strings.Fields(input))
+	switch wildcardOptions(ctx).Strategy {
+	case PrefixWildcard, WildcardFieldMatch:
+		return clause.ClauseCost{Cost: WildcardCost * float64(terms), Terms: terms}
+	case NGramMatch:
+		return clause.ClauseCost{Cost: TermCost * float64(terms), Terms: terms}
+	default:
+		return QueryStringCost(input, false)
+	}
+}
@@ -0,0 +1,209 @@
+package clauseutils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+)
+
+func TestSanitizeQueryString(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "foo"},
+		{"foo OR bar", "foo OR bar"},
+		{"foo AND bar", `foo \AND bar`},
+		{"NOT foo", `\NOT foo`},
+		{"label:foo", `label\:foo`},
+		{"foo~2", `foo\~2`},
+		{"/fo.*/", `\/fo.*\/`},
+		{"foo && bar || baz", `foo \&\& bar \|\| baz`},
+		{"(foo) [a TO b] {c}", `\(foo\) \[a TO b\] \{c\}`},
+		{"+foo -bar !baz ^2 =x <y >z", `\+foo \-bar \!baz \^2 \=x \<y \>z`},
+		{"fo*o? \"a b\" \\x", "fo*o? \"a b\" \\x"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue := SanitizeQueryString(c.input)
+			if gotValue != c.expected {
+				t.Errorf("Got %q but expected %q", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestAddSafeImplicitWildcard(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "*foo*"},
+		{"foo bar", "*foo* *bar*"},
+		{"foo OR bar", "*foo* *bar*"},
+		{"a:b", `*a\:b*`},
+		{"foo AND bar", `*foo* *\AND* *bar*`},
+		{"sample(1)", `*sample\(1\)*`},
+		{"*foo AND x:y", `*foo \AND x\:y`},
+		{"\"foo bar\"", "\"foo bar\""},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue := AddSafeImplicitWildcard(c.input)
+			if gotValue != c.expected {
+				t.Errorf("Got %q but expected %q", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestAddImplicitPrefixWildcard(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "foo*"},
+		{"foo bar", "foo* bar*"},
+		{"foo OR bar", "foo* bar*"},
+		{"a:b", `a\:b*`},
+		{"*foo ?bar baz*", "foo bar baz*"},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue := AddImplicitPrefixWildcard(c.input)
+			if gotValue != c.expected {
+				t.Errorf("Got %q but expected %q", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestWildcardQuery(t *testing.T) {
+	cases := []struct {
+		options  *WildcardOptions
+		input    string
+		expected elastic.Query
+	}{
+		{nil, "foo bar", elastic.NewQueryStringQuery("*foo* *bar*").Field("label")},
+		{&WildcardOptions{Strategy: ContainsWildcard}, "a:b", elastic.NewQueryStringQuery(`*a\:b*`).Field("label")},
+		{&WildcardOptions{Strategy: PrefixWildcard}, "foo bar", elastic.NewQueryStringQuery("foo* bar*").Field("label")},
+		{&WildcardOptions{Strategy: NGramMatch}, "foo bar", elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+			elastic.NewMatchQuery("label.ngram", "foo").Operator("and"),
+			elastic.NewMatchQuery("label.ngram", "bar").Operator("and"),
+		)},
+		{&WildcardOptions{Strategy: NGramMatch}, "foo OR bar", elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+			elastic.NewMatchQuery("label.ngram", "foo").Operator("and"),
+			elastic.NewMatchQuery("label.ngram", "bar").Operator("and"),
+		)},
+		{&WildcardOptions{Strategy: NGramMatch, NGramSubfield: "grams"}, "foo", elastic.NewMatchQuery("label.grams", "foo").Operator("and")},
+		{&WildcardOptions{Strategy: WildcardFieldMatch}, "f*o", elastic.NewWildcardQuery("label.wildcard", `*f\*o*`)},
+		{&WildcardOptions{Strategy: WildcardFieldMatch}, "foo OR bar", elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+			elastic.NewWildcardQuery("label.wildcard", "*foo*"),
+			elastic.NewWildcardQuery("label.wildcard", "*bar*"),
+		)},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%+v-%s", c.options, c.input), func(t *testing.T) {
+			ctx := context.Background()
+			if c.options != nil {
+				ctx = WithWildcardOptions(ctx, *c.options)
+			}
+			source, err := WildcardQuery(ctx, "label", c.input).Source()
+			if err != nil {
+				t.Error("Source get on wildcard query failed")
+			}
+			expsource, err := c.expected.Source()
+			if err != nil {
+				t.Error("Source get on expected query failed")
+			}
+			if !reflect.DeepEqual(source, expsource) {
+				t.Errorf("Value %+v and expected value %+v were not deeply equal", source, expsource)
+			}
+		})
+	}
+}
+
+func TestWildcardSearchCost(t *testing.T) {
+	prefix := WithWildcardOptions(context.Background(), WildcardOptions{Strategy: PrefixWildcard})
+
+	if cost := WildcardSearchCost(context.Background(), "foo bar", false); cost.LeadingWildcards != 2 {
+		t.Errorf("contains strategy cost %+v should have two leading wildcards", cost)
+	}
+	if cost := WildcardSearchCost(prefix, "foo bar", false); cost.LeadingWildcards != 0 || cost.Terms != 2 {
+		t.Errorf("prefix strategy cost %+v should have two terms and no leading wildcards", cost)
+	}
+}
+
+func TestEscapeQueryString(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "foo"},
+		{"foo bar", "foo bar"},
+		{"+", `\+`},
+		{"-", `\-`},
+		{"=", `\=`},
+		{"&&", `\&\&`},
+		{"||", `\|\|`},
+		{">", `\>`},
+		{"<", `\<`},
+		{"!", `\!`},
+		{"(", `\(`},
+		{")", `\)`},
+		{"{", `\{`},
+		{"}", `\}`},
+		{"[", `\[`},
+		{"]", `\]`},
+		{"^", `\^`},
+		{`"`, `\"`},
+		{"~", `\~`},
+		{"*", `\*`},
+		{"?", `\?`},
+		{":", `\:`},
+		{`\`, `\\`},
+		{"/", `\/`},
+		{"foo AND bar", `foo \AND bar`},
+		{"foo OR bar", `foo \OR bar`},
+		{"NOT foo", `\NOT foo`},
+		{"sample(1) [final].txt", `sample\(1\) \[final\].txt`},
+		{"a:b", `a\:b`},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			gotValue := EscapeQueryString(c.input)
+			if gotValue != c.expected {
+				t.Errorf("Got %q but expected %q", gotValue, c.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteQueryString(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"liver", `"liver"`},
+		{"Mus musculus", `"Mus musculus"`},
+		{`say "hi" (twice)`, `"say \"hi\" \(twice\)"`},
+		{"https://example.org/a?b", `"https\:\/\/example.org\/a\?b"`},
+	}
+
+	for _, c := range cases {
+		if quoted := QuoteQueryString(c.input); quoted != c.expected {
+			t.Errorf("Got %s from QuoteQueryString(%q), expected %s", quoted, c.input, c.expected)
+		}
+	}
+}
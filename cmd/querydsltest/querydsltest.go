@@ -8,20 +8,23 @@ import (
 	"text/template"
 
 	"github.com/cyverse-de/querydsl/v2"
+	"github.com/cyverse-de/querydsl/v2/clause/accessibleby"
 	"github.com/cyverse-de/querydsl/v2/clause/created"
+	"github.com/cyverse-de/querydsl/v2/clause/doctype"
 	"github.com/cyverse-de/querydsl/v2/clause/label"
 	"github.com/cyverse-de/querydsl/v2/clause/metadata"
 	"github.com/cyverse-de/querydsl/v2/clause/modified"
 	"github.com/cyverse-de/querydsl/v2/clause/owner"
 	"github.com/cyverse-de/querydsl/v2/clause/path"
 	"github.com/cyverse-de/querydsl/v2/clause/permissions"
+	"github.com/cyverse-de/querydsl/v2/clause/sharedwith"
 	"github.com/cyverse-de/querydsl/v2/clause/size"
 	"github.com/cyverse-de/querydsl/v2/clause/tag"
 )
 
 func printDocumentation(qd *querydsl.QueryDSL) error {
 	tmpl, err := template.New("documentation").Parse(`Available clause types:
-{{ range $k, $v := . }}{{ $k }}: {{ if $v.Summary }}{{ $v.Summary }}{{ else }}(no summary provided){{end}}
+{{ range $k, $v := . }}{{ $k }}: {{ if $v.Summary }}{{ $v.Summary }}{{ else }}(no summary provided){{end}}{{ if $v.Kinds }} (applies to: {{ range $i, $kind := $v.Kinds }}{{ if $i }}, {{ end }}{{ $kind }}{{ end }}){{ end }}
     Arguments:{{ if $v.Args }}{{ range $ak, $av := $v.Args }}
         {{ $ak }} ({{ $av.Type }}): {{ $av.Summary }}
 {{ end }}{{ else }} (no arguments)
@@ -31,7 +34,15 @@ func printDocumentation(qd *querydsl.QueryDSL) error {
 	}
 
 	err = tmpl.Execute(os.Stdout, qd.GetDocumentation())
-	return err
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Metadata namespaces:")
+	for _, ns := range qd.GetMetadataNamespaces() {
+		fmt.Printf("    %s (%s): %s\n", ns.Name, ns.NestedPath(), ns.Documentation)
+	}
+	return nil
 }
 
 func main() {
@@ -45,6 +56,9 @@ func main() {
 	created.Register(qd)
 	modified.Register(qd)
 	size.Register(qd)
+	doctype.Register(qd)
+	sharedwith.Register(qd)
+	accessibleby.Register(qd)
 
 	err := printDocumentation(qd)
 	if err != nil {
@@ -53,8 +67,8 @@ func main() {
 	}
 
 	var jsonBlob = []byte(`{
-		"all": [{"type": "path", "args": {"prefix": "/iplant/home"}}, {"type": "label", "args": {"label": "PDAP.fel.tree"}}, {"type": "permissions", "args": {"users": ["mian", "ipctest#iplant", "foo#bar", "baz"], "permission": "write"}}, {"type": "size", "args": {"from": "1KB", "to": "  4.8 GB  "}}],
-		"any": [{"type": "owner", "args": {"owner": "ipctest"}},{"type": "metadata", "args": {"attribute": "foo", "value": "bar", "attribute_exact": true}},{"type": "metadata", "args": {"attribute": "foo", "value": "bar", "attribute_exact": true, "value_exact": true, "metadata_types": ["irods"]}}, {"type": "tag", "args": {"tags": ["dummy-tag-value"]}}, {"type": "created", "args": {"from": "2017-09-23T00:00:00.000Z"}}, {"type": "modified", "args": {"to": "2017-09-23T00:00:00.000-07:00"}}],
+		"all": [{"type": "path", "args": {"prefix": "/iplant/home"}}, {"type": "label", "args": {"label": "PDAP.fel.tree"}}, {"type": "permissions", "args": {"users": ["mian", "ipctest#iplant", "foo#bar", "baz"], "permission": "write"}}, {"type": "size", "args": {"from": "1KB", "to": "  4.8 GB  "}}, {"type": "type", "args": {"type": "file"}}],
+		"any": [{"type": "owner", "args": {"owner": "ipctest"}},{"type": "shared_with", "args": {"user": "ipctest#iplant"}},{"type": "metadata", "args": {"attribute": "foo", "value": "bar", "attribute_exact": true}},{"type": "metadata", "args": {"attribute": "foo", "value": "bar", "attribute_exact": true, "value_exact": true, "metadata_types": ["irods"]}}, {"type": "tag", "args": {"tags": ["dummy-tag-value"]}}, {"type": "created", "args": {"from": "2017-09-23T00:00:00.000Z"}}, {"type": "modified", "args": {"to": "2017-09-23T00:00:00.000-07:00"}}],
 		"none": [{"type": "permissions", "args": {"permission": "read", "users": ["mian#iplant", "ipctest#iplant"]}}]
 	}`)
 	var query querydsl.Query
@@ -3,11 +3,13 @@ package querydsl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 
 	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
 	"github.com/olivere/elastic/v7"
 )
 
@@ -16,6 +18,22 @@ type QueryDSL struct {
 	clauseProcessors    map[clause.ClauseType]clause.ClauseProcessor
 	clauseDocumentation map[clause.ClauseType]clause.ClauseDocumentation
 	clauseSummarizers   map[clause.ClauseType]clause.ClauseSummarizer
+	kindIndices         map[clause.DocumentKind]string
+	userResolver        clauseutils.UserResolver
+	groupResolver       clauseutils.GroupResolver
+	maxGroupMembers     int
+	wildcardOptions     *clauseutils.WildcardOptions
+	regexpOptions       *clauseutils.RegexpOptions
+	sizeUnitSystem      *clauseutils.SizeUnitSystem
+	templateProvider    clauseutils.TemplateProvider
+	templateNamespace   string
+	metadataNamespaces  clauseutils.MetadataNamespaces
+	tagResolver         clauseutils.TagResolver
+	tagLookupOptions    *clauseutils.TagLookupOptions
+	clauseCosters       map[clause.ClauseType]clause.ClauseCoster
+	clausePolicies      map[clause.ClauseType]clause.ClausePolicy
+	authorizer          Authorizer
+	limits              Limits
 }
 
 // Query represents a boolean query
@@ -67,7 +85,7 @@ func (c *GenericClause) Summarize(ctx context.Context, qd *QueryDSL) string {
 func (c *Clause) Summarize(ctx context.Context, qd *QueryDSL) string {
 	clauseSummarizers := qd.GetSummarizers()
 	if summarizer, exists := clauseSummarizers[c.Type]; exists {
-		summary, err := summarizer(ctx, c.Args)
+		summary, err := summarizer(qd.withDefaults(ctx), c.Args)
 		if err != nil {
 			return fmt.Sprintf("{ERR:%s}", err)
 		}
@@ -108,6 +126,49 @@ func (q *Query) Summarize(ctx context.Context, qd *QueryDSL) string {
 	return strings.TrimSpace(strings.Join([]string{all, any, none}, " "))
 }
 
+/// WALKING QUERIES
+
+// walk calls fn for every Clause contained in a Query, however deeply nested,
+// along with its depth (clauses directly in the Query have depth 1). Walking
+// stops at the first error returned by fn.
+func (q *Query) walk(depth int, fn func(c *Clause, depth int) error) error {
+	for _, section := range [][]*GenericClause{q.All, q.Any, q.None} {
+		for _, gc := range section {
+			var err error
+			if gc.IsQuery() {
+				query := Query{All: gc.All, Any: gc.Any, None: gc.None}
+				err = query.walk(depth+1, fn)
+			} else if gc.IsClause() {
+				err = fn(&Clause{Type: gc.Type, Args: gc.Args}, depth)
+			} else {
+				err = fmt.Errorf("GenericClause %+v is neither a properly-formatted Query nor a Clause", gc)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/// VALIDATING QUERIES
+
+// Validate checks that a Clause has a registered processor, that it is authorized, and that its arguments can be processed
+func (c *Clause) Validate(ctx context.Context, qd *QueryDSL) error {
+	_, err := c.Translate(ctx, qd)
+	return err
+}
+
+// Validate checks that a Query is within the QueryDSL's limits, then checks every Clause within it as with Clause.Validate, stopping at the first error
+func (q *Query) Validate(ctx context.Context, qd *QueryDSL) error {
+	if err := qd.CheckLimits(ctx, q); err != nil {
+		return err
+	}
+	return q.walk(1, func(c *Clause, _ int) error {
+		return c.Validate(ctx, qd)
+	})
+}
+
 /// TRANSLATING QUERIES
 
 // Translate turns a GenericClause into an elastic.Query
@@ -128,6 +189,10 @@ func (c *GenericClause) Translate(ctx context.Context, qd *QueryDSL) (elastic.Qu
 func (c *Clause) Translate(ctx context.Context, qd *QueryDSL) (elastic.Query, error) {
 	clauseProcessors := qd.GetProcessors()
 	if processor, exists := clauseProcessors[c.Type]; exists {
+		ctx = qd.withDefaults(ctx)
+		if err := qd.Authorize(ctx, c.Type, c.Args); err != nil {
+			return nil, err
+		}
 		return processor(ctx, c.Args)
 	}
 	return nil, fmt.Errorf("No processor found for type '%s'", c.Type)
@@ -165,8 +230,18 @@ func launchClauseTranslators(ctx context.Context, qd *QueryDSL, clauses []*Gener
 	}(waitgroup, &innerwg)
 }
 
-// Translate turns a Query into an elastic.Query by way of translating everything contained within
+type limitsCheckedKey struct{}
+
+// Translate turns a Query into an elastic.Query by way of translating everything contained within.
+// The outermost Query is first checked against the QueryDSL's limits.
 func (q *Query) Translate(ctx context.Context, qd *QueryDSL) (elastic.Query, error) {
+	if ctx.Value(limitsCheckedKey{}) == nil {
+		if err := qd.CheckLimits(ctx, q); err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, limitsCheckedKey{}, true)
+	}
+
 	baseQuery := elastic.NewBoolQuery()
 
 	// Result channels
@@ -207,12 +282,524 @@ func (q *Query) Translate(ctx context.Context, qd *QueryDSL) (elastic.Query, err
 	}
 }
 
+// ScopedQuery is the result of translating a Query restricted to a set of document kinds
+type ScopedQuery struct {
+	// Query is the translated query, filtered to the requested kinds
+	Query elastic.Query
+	// Kinds are the document kinds the query was restricted to
+	Kinds []clause.DocumentKind
+	// Indices are the indices configured for Kinds with SetKindIndex, without duplicates. Kinds with no configured index are skipped.
+	Indices []string
+	// Warnings describe clauses that can never match one of the requested kinds
+	Warnings []string
+}
+
+// kindWarnings lists a warning for each clause in a Query which is documented as never matching one of the given kinds
+func (q *Query) kindWarnings(qd *QueryDSL, kinds []clause.DocumentKind) []string {
+	var warnings []string
+	documentation := qd.GetDocumentation()
+	_ = q.walk(1, func(c *Clause, _ int) error {
+		doc, exists := documentation[c.Type]
+		if !exists {
+			return nil
+		}
+		for _, kind := range kinds {
+			if !doc.AppliesTo(kind) {
+				warnings = append(warnings, fmt.Sprintf("Clause of type '%s' can never match objects of kind '%s'", c.Type, kind))
+			}
+		}
+		return nil
+	})
+	return warnings
+}
+
+// TranslateScoped turns a Query into an elastic.Query which only matches documents of the given kinds.
+// The returned ScopedQuery also carries any configured indices for those kinds and warnings about clauses that cannot match them.
+func (qd *QueryDSL) TranslateScoped(ctx context.Context, query *Query, kinds ...clause.DocumentKind) (*ScopedQuery, error) {
+	if len(kinds) == 0 {
+		return nil, errors.New("No document kinds were passed, cannot scope query.")
+	}
+
+	translated, err := query.Translate(ctx, qd)
+	if err != nil {
+		return nil, err
+	}
+
+	var kindFilter elastic.Query
+	if len(kinds) == 1 {
+		kindFilter = elastic.NewTermQuery(clause.DocumentKindField, string(kinds[0]))
+	} else {
+		values := make([]interface{}, len(kinds))
+		for i, kind := range kinds {
+			values[i] = string(kind)
+		}
+		kindFilter = elastic.NewTermsQuery(clause.DocumentKindField, values...)
+	}
+
+	var indices []string
+	seen := make(map[string]bool)
+	for _, kind := range kinds {
+		if index, exists := qd.kindIndices[kind]; exists && !seen[index] {
+			seen[index] = true
+			indices = append(indices, index)
+		}
+	}
+
+	return &ScopedQuery{
+		Query:    elastic.NewBoolQuery().Must(translated).Filter(kindFilter),
+		Kinds:    kinds,
+		Indices:  indices,
+		Warnings: query.kindWarnings(qd, kinds),
+	}, nil
+}
+
+/// TRANSLATING QUERIES ON BEHALF OF USERS
+
+// Principal identifies the user a query is translated on behalf of
+type Principal struct {
+	// Username is the principal's username, qualified or not
+	Username string
+	// Groups are the groups the principal belongs to, which may also be granted permissions on objects
+	Groups []string
+	// Roles are the principal's roles, such as 'admin', used by clause policies
+	Roles []string
+}
+
+// HasRole checks if the principal has any of the given roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the given Principal, and its username as the current user
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	ctx = context.WithValue(ctx, principalKey{}, principal)
+	return clauseutils.WithCurrentUser(ctx, principal.Username)
+}
+
+// PrincipalFromContext returns the Principal carried by ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// securityFilter creates a query matching only objects the principal, or one of its groups, has some permission on.
+// It is built only from exact usernames, so no principal or group name can widen it with wildcards.
+func securityFilter(ctx context.Context, principal *Principal) (elastic.Query, error) {
+	var terms []string
+	for _, name := range append([]string{principal.Username}, principal.Groups...) {
+		exact, err := clauseutils.ExactUsernames(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot create security filter: %w", err)
+		}
+		terms = append(terms, exact...)
+	}
+
+	inner := elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+		elastic.NewTermsQuery("userPermissions.user", clauseutils.StringsToInterfaces(terms)...),
+	)
+	return elastic.NewNestedQuery("userPermissions", inner), nil
+}
+
+// TranslateFor turns a Query into an elastic.Query on behalf of a principal. The
+// translated query is always combined with a filter matching only objects the
+// principal or its groups have at least read permission on. The filter sits
+// outside the translated query, so nothing within the query can remove it.
+func (qd *QueryDSL) TranslateFor(ctx context.Context, principal *Principal, query *Query) (elastic.Query, error) {
+	if principal == nil || principal.Username == "" {
+		return nil, errors.New("No principal was passed, cannot translate query.")
+	}
+
+	ctx = qd.withDefaults(WithPrincipal(ctx, principal))
+
+	filter, err := securityFilter(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	translated, err := query.Translate(ctx, qd)
+	if err != nil {
+		return nil, err
+	}
+
+	return elastic.NewBoolQuery().Must(translated).Filter(filter), nil
+}
+
+/// LIMITING QUERIES
+
+// Limits bounds the size and estimated expense of queries. Zero values mean no limit.
+type Limits struct {
+	// MaxDepth is the deepest level of nesting allowed, where clauses directly within a query are at depth 1
+	MaxDepth int
+	// MaxClauses is the most clauses allowed across the entire query
+	MaxClauses int
+	// MaxTermsPerClause is the most terms allowed in any single clause. Groups count as their members when they can be looked
+	// up, and otherwise as the most members they may expand to, so should be allowed at least that many terms.
+	MaxTermsPerClause int
+	// MaxLeadingWildcards is the most patterns starting with a wildcard allowed across the entire query
+	MaxLeadingWildcards int
+	// ForbidLeadingWildcards rejects any pattern starting with a wildcard
+	ForbidLeadingWildcards bool
+	// MaxTagLookups is the most tag terms lookups allowed across the entire query
+	MaxTagLookups int
+	// MaxCost is the highest total estimated cost allowed
+	MaxCost float64
+}
+
+// ErrLimitExceeded is wrapped by errors returned when a query exceeds the QueryDSL's limits
+var ErrLimitExceeded = errors.New("query limit exceeded")
+
+// QueryCost is an estimate of how expensive a whole query is
+type QueryCost struct {
+	// Cost is the sum of the estimated cost of every clause
+	Cost float64 `json:"cost"`
+	// Clauses is the number of clauses in the query
+	Clauses int `json:"clauses"`
+	// Depth is the deepest level of nesting any clause appears at
+	Depth int `json:"depth"`
+	// Terms is the total number of terms across all clauses
+	Terms int `json:"terms"`
+	// MaxClauseTerms is the largest number of terms in any single clause
+	MaxClauseTerms int `json:"max_clause_terms"`
+	// LeadingWildcards is the total number of patterns starting with a wildcard
+	LeadingWildcards int `json:"leading_wildcards"`
+	// TagLookups is the total number of tag terms lookups
+	TagLookups int `json:"tag_lookups"`
+}
+
+// Cost estimates how expensive a query is, using the ClauseCoster registered for each clause type or clauseutils.DefaultClauseCost
+func (qd *QueryDSL) Cost(ctx context.Context, query *Query) (*QueryCost, error) {
+	ctx = qd.withDefaults(ctx)
+	total := &QueryCost{}
+	err := query.walk(1, func(c *Clause, depth int) error {
+		if _, exists := qd.clauseProcessors[c.Type]; !exists {
+			return fmt.Errorf("No processor found for type '%s'", c.Type)
+		}
+
+		coster, exists := qd.clauseCosters[c.Type]
+		if !exists {
+			coster = clauseutils.DefaultClauseCost
+		}
+		cost, err := coster(ctx, c.Args)
+		if err != nil {
+			return err
+		}
+
+		total.Cost += cost.Cost
+		total.Clauses++
+		total.Terms += cost.Terms
+		total.LeadingWildcards += cost.LeadingWildcards
+		total.TagLookups += cost.TagLookups
+		if depth > total.Depth {
+			total.Depth = depth
+		}
+		if cost.Terms > total.MaxClauseTerms {
+			total.MaxClauseTerms = cost.Terms
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// CheckLimits estimates the cost of a query and returns an error wrapping ErrLimitExceeded if it exceeds any of the QueryDSL's limits
+func (qd *QueryDSL) CheckLimits(ctx context.Context, query *Query) error {
+	limits := qd.limits
+	if limits == (Limits{}) {
+		return nil
+	}
+
+	cost, err := qd.Cost(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case limits.MaxDepth > 0 && cost.Depth > limits.MaxDepth:
+		return fmt.Errorf("%w: query is nested %d levels deep, but at most %d are allowed", ErrLimitExceeded, cost.Depth, limits.MaxDepth)
+	case limits.MaxClauses > 0 && cost.Clauses > limits.MaxClauses:
+		return fmt.Errorf("%w: query has %d clauses, but at most %d are allowed", ErrLimitExceeded, cost.Clauses, limits.MaxClauses)
+	case limits.MaxTermsPerClause > 0 && cost.MaxClauseTerms > limits.MaxTermsPerClause:
+		return fmt.Errorf("%w: a clause has %d terms, but at most %d are allowed", ErrLimitExceeded, cost.MaxClauseTerms, limits.MaxTermsPerClause)
+	case limits.ForbidLeadingWildcards && cost.LeadingWildcards > 0:
+		return fmt.Errorf("%w: query has patterns starting with a wildcard, which are not allowed", ErrLimitExceeded)
+	case limits.MaxLeadingWildcards > 0 && cost.LeadingWildcards > limits.MaxLeadingWildcards:
+		return fmt.Errorf("%w: query has %d patterns starting with a wildcard, but at most %d are allowed", ErrLimitExceeded, cost.LeadingWildcards, limits.MaxLeadingWildcards)
+	case limits.MaxTagLookups > 0 && cost.TagLookups > limits.MaxTagLookups:
+		return fmt.Errorf("%w: query has %d tag lookups, but at most %d are allowed", ErrLimitExceeded, cost.TagLookups, limits.MaxTagLookups)
+	case limits.MaxCost > 0 && cost.Cost > limits.MaxCost:
+		return fmt.Errorf("%w: query has an estimated cost of %g, but at most %g is allowed", ErrLimitExceeded, cost.Cost, limits.MaxCost)
+	}
+	return nil
+}
+
+// SetLimits sets the limits queries are checked against before translation and during validation
+func (qd *QueryDSL) SetLimits(limits Limits) {
+	qd.limits = limits
+}
+
+/// AUTHORIZING CLAUSES
+
+// Authorizer decides whether a clause may be used in the given context, returning a non-nil error if not
+type Authorizer func(ctx context.Context, clauseType clause.ClauseType, args map[string]interface{}) error
+
+// ErrUnauthorized is wrapped by errors returned when a clause is not permitted by its policy
+var ErrUnauthorized = errors.New("clause not authorized")
+
+// sameUser checks if an argument value refers only to the given username. Both are resolved to exact qualified
+// usernames, and every username the value resolves to must be one the username resolves to, so an unqualified value
+// can't reach other zones. Values resolving to wildcards never match.
+func sameUser(ctx context.Context, value, username string) bool {
+	allowed, err := clauseutils.ExactUsernames(ctx, username)
+	if err != nil {
+		return false
+	}
+	resolved, err := clauseutils.ExactUsernames(ctx, value)
+	if err != nil {
+		return false
+	}
+	for _, term := range resolved {
+		found := false
+		for _, a := range allowed {
+			if term == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSelfOnlyArg checks that an argument named in a policy's SelfOnlyArgs references only the given username
+func checkSelfOnlyArg(ctx context.Context, clauseType clause.ClauseType, argName string, arg interface{}, username string) error {
+	var values []string
+	switch v := arg.(type) {
+	case nil:
+	case string:
+		values = []string{v}
+	case []string:
+		values = v
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%w: argument '%s' of clause type '%s' has an unexpected value %v", ErrUnauthorized, argName, clauseType, item)
+			}
+			values = append(values, s)
+		}
+	default:
+		return fmt.Errorf("%w: argument '%s' of clause type '%s' has an unexpected value %v", ErrUnauthorized, argName, clauseType, v)
+	}
+
+	for _, value := range values {
+		if !sameUser(ctx, value, username) {
+			return fmt.Errorf("%w: argument '%s' of clause type '%s' may only reference the current user", ErrUnauthorized, argName, clauseType)
+		}
+	}
+	return nil
+}
+
+// checkPolicy enforces a ClausePolicy against the principal carried by ctx
+func checkPolicy(ctx context.Context, clauseType clause.ClauseType, policy clause.ClausePolicy, args map[string]interface{}) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: clause of type '%s' requires a principal", ErrUnauthorized, clauseType)
+	}
+
+	if len(policy.AllowedRoles) > 0 && !principal.HasRole(policy.AllowedRoles...) {
+		return fmt.Errorf("%w: clause of type '%s' requires one of the roles %v", ErrUnauthorized, clauseType, policy.AllowedRoles)
+	}
+
+	if principal.HasRole(policy.ExemptRoles...) {
+		return nil
+	}
+
+	// arguments are decoded without regard to case, so they must be checked the same way
+	for _, policyArg := range policy.SelfOnlyArgs {
+		for argName, arg := range args {
+			if !strings.EqualFold(argName, policyArg) {
+				continue
+			}
+			if err := checkSelfOnlyArg(ctx, clauseType, argName, arg, principal.Username); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Authorize checks whether a clause may be used, first against any ClausePolicy set for its type, then with any Authorizer set on the QueryDSL.
+// It is run for every clause by Translate and Validate.
+func (qd *QueryDSL) Authorize(ctx context.Context, clauseType clause.ClauseType, args map[string]interface{}) error {
+	ctx = qd.withDefaults(ctx)
+	if policy, exists := qd.clausePolicies[clauseType]; exists {
+		if err := checkPolicy(ctx, clauseType, policy, args); err != nil {
+			return err
+		}
+	}
+	if qd.authorizer != nil {
+		return qd.authorizer(ctx, clauseType, args)
+	}
+	return nil
+}
+
+// SetAuthorizer sets a function run to authorize every clause during translation and validation
+func (qd *QueryDSL) SetAuthorizer(authorizer Authorizer) {
+	qd.authorizer = authorizer
+}
+
+// SetClausePolicy restricts use of a clause type to principals satisfying the given policy
+func (qd *QueryDSL) SetClausePolicy(clausetype clause.ClauseType, policy clause.ClausePolicy) {
+	qd.clausePolicies[clausetype] = policy
+}
+
 // New creates a new empty QueryDSL
 func New() *QueryDSL {
 	processors := make(map[clause.ClauseType]clause.ClauseProcessor)
 	documentation := make(map[clause.ClauseType]clause.ClauseDocumentation)
 	summarizers := make(map[clause.ClauseType]clause.ClauseSummarizer)
-	return &QueryDSL{clauseProcessors: processors, clauseDocumentation: documentation, clauseSummarizers: summarizers}
+	kindIndices := make(map[clause.DocumentKind]string)
+	costers := make(map[clause.ClauseType]clause.ClauseCoster)
+	policies := make(map[clause.ClauseType]clause.ClausePolicy)
+	return &QueryDSL{clauseProcessors: processors, clauseDocumentation: documentation, clauseSummarizers: summarizers, clauseCosters: costers, kindIndices: kindIndices, clausePolicies: policies}
+}
+
+// withDefaults returns a copy of ctx carrying the settings configured on this QueryDSL, for use by clause processors and summarizers.
+// Settings already present in ctx take precedence.
+func (qd *QueryDSL) withDefaults(ctx context.Context) context.Context {
+	if _, ok := clauseutils.UserResolverFromContext(ctx); !ok && qd.userResolver != nil {
+		ctx = clauseutils.WithUserResolver(ctx, qd.userResolver)
+	}
+	if _, ok := clauseutils.GroupResolverFromContext(ctx); !ok && qd.groupResolver != nil {
+		ctx = clauseutils.WithGroupResolver(ctx, qd.groupResolver)
+	}
+	if _, ok := clauseutils.MaxGroupMembersFromContext(ctx); !ok && qd.maxGroupMembers > 0 {
+		ctx = clauseutils.WithMaxGroupMembers(ctx, qd.maxGroupMembers)
+	}
+	if _, ok := clauseutils.WildcardOptionsFromContext(ctx); !ok && qd.wildcardOptions != nil {
+		ctx = clauseutils.WithWildcardOptions(ctx, *qd.wildcardOptions)
+	}
+	if _, ok := clauseutils.RegexpOptionsFromContext(ctx); !ok && qd.regexpOptions != nil {
+		ctx = clauseutils.WithRegexpOptions(ctx, *qd.regexpOptions)
+	}
+	if _, ok := clauseutils.SizeUnitSystemFromContext(ctx); !ok && qd.sizeUnitSystem != nil {
+		ctx = clauseutils.WithSizeUnitSystem(ctx, *qd.sizeUnitSystem)
+	}
+	if _, ok := clauseutils.TemplateProviderFromContext(ctx); !ok && qd.templateProvider != nil {
+		ctx = clauseutils.WithTemplateProvider(ctx, qd.templateProvider)
+	}
+	if _, ok := clauseutils.TemplateNamespaceFromContext(ctx); !ok && qd.templateNamespace != "" {
+		ctx = clauseutils.WithTemplateNamespace(ctx, qd.templateNamespace)
+	}
+	if _, ok := clauseutils.MetadataNamespacesFromContext(ctx); !ok && qd.metadataNamespaces != nil {
+		ctx = clauseutils.WithMetadataNamespaces(ctx, qd.metadataNamespaces)
+	}
+	if _, ok := clauseutils.TagResolverFromContext(ctx); !ok && qd.tagResolver != nil {
+		ctx = clauseutils.WithTagResolver(ctx, qd.tagResolver)
+	}
+	if _, ok := clauseutils.TagLookupOptionsFromContext(ctx); !ok && qd.tagLookupOptions != nil {
+		ctx = clauseutils.WithTagLookupOptions(ctx, *qd.tagLookupOptions)
+	}
+	return ctx
+}
+
+// SetUserResolver sets the UserResolver clauses use to qualify usernames, unless one is provided in the context
+func (qd *QueryDSL) SetUserResolver(resolver clauseutils.UserResolver) {
+	qd.userResolver = resolver
+}
+
+// SetGroupResolver sets the GroupResolver clauses use to expand groups to their members, unless one is provided in the context.
+// Wrap it with clauseutils.NewCachingGroupResolver to cache lookups across queries.
+func (qd *QueryDSL) SetGroupResolver(resolver clauseutils.GroupResolver) {
+	qd.groupResolver = resolver
+}
+
+// SetMaxGroupMembers limits how many usernames the groups in a single clause may expand to, unless a limit is provided in the context
+func (qd *QueryDSL) SetMaxGroupMembers(max int) {
+	qd.maxGroupMembers = max
+}
+
+// SetWildcardOptions sets how clauses match inexact text searches, unless options are provided in the context
+func (qd *QueryDSL) SetWildcardOptions(options clauseutils.WildcardOptions) {
+	qd.wildcardOptions = &options
+}
+
+// SetRegexpOptions sets the limits placed on regular expression searches, unless options are provided in the context
+func (qd *QueryDSL) SetRegexpOptions(options clauseutils.RegexpOptions) {
+	qd.regexpOptions = &options
+}
+
+// SetSizeUnitSystem sets whether size units like KB are powers of 1000 or 1024 bytes, unless a system is provided in the context
+func (qd *QueryDSL) SetSizeUnitSystem(system clauseutils.SizeUnitSystem) {
+	qd.sizeUnitSystem = &system
+}
+
+// SetTemplateProvider sets the TemplateProvider clauses use to look up metadata templates, unless one is provided in the context
+func (qd *QueryDSL) SetTemplateProvider(provider clauseutils.TemplateProvider) {
+	qd.templateProvider = provider
+}
+
+// SetTemplateNamespace sets the name of the metadata namespace holding AVUs filled in from metadata templates, which is
+// clauseutils.DefaultTemplateNamespace unless set, unless one is provided in the context
+func (qd *QueryDSL) SetTemplateNamespace(name string) {
+	qd.templateNamespace = name
+}
+
+// SetMetadataNamespaces replaces the metadata namespaces clauses search, unless namespaces are provided in the context
+func (qd *QueryDSL) SetMetadataNamespaces(namespaces clauseutils.MetadataNamespaces) {
+	qd.metadataNamespaces = namespaces
+}
+
+// AddMetadataNamespace adds a metadata namespace for clauses to search, alongside the default irods and cyverse namespaces
+// unless SetMetadataNamespaces was used. A namespace with the same name as an existing one replaces it.
+func (qd *QueryDSL) AddMetadataNamespace(namespace clauseutils.MetadataNamespace) {
+	if qd.metadataNamespaces == nil {
+		qd.metadataNamespaces = append(clauseutils.MetadataNamespaces{}, clauseutils.DefaultMetadataNamespaces...)
+	}
+	for i, ns := range qd.metadataNamespaces {
+		if ns.Name == namespace.Name {
+			qd.metadataNamespaces[i] = namespace
+			return
+		}
+	}
+	qd.metadataNamespaces = append(qd.metadataNamespaces, namespace)
+}
+
+// GetMetadataNamespaces returns the metadata namespaces clauses search, along with their documentation
+func (qd *QueryDSL) GetMetadataNamespaces() clauseutils.MetadataNamespaces {
+	if qd.metadataNamespaces == nil {
+		return clauseutils.DefaultMetadataNamespaces
+	}
+	return qd.metadataNamespaces
+}
+
+// SetTagResolver sets the TagResolver clauses use to look up tags by name, unless one is provided in the context
+func (qd *QueryDSL) SetTagResolver(resolver clauseutils.TagResolver) {
+	qd.tagResolver = resolver
+}
+
+// SetTagLookupOptions sets the index, type, and path of the tag documents clauses look up, unless options are provided in the context
+func (qd *QueryDSL) SetTagLookupOptions(options clauseutils.TagLookupOptions) {
+	qd.tagLookupOptions = &options
+}
+
+// SetKindIndex records which Elasticsearch index holds documents of the given kind, for use by TranslateScoped
+func (qd *QueryDSL) SetKindIndex(kind clause.DocumentKind, index string) {
+	qd.kindIndices[kind] = index
 }
 
 // AddClauseType takes a string (as clause.ClauseType), a function to process,
@@ -229,6 +816,11 @@ func (qd *QueryDSL) AddClauseTypeSummarized(clausetype clause.ClauseType, proces
 	qd.clauseSummarizers[clausetype] = summarizer
 }
 
+// SetClauseCoster registers a function estimating the cost of clauses of the given type, for use by Cost and CheckLimits
+func (qd *QueryDSL) SetClauseCoster(clausetype clause.ClauseType, coster clause.ClauseCoster) {
+	qd.clauseCosters[clausetype] = coster
+}
+
 // GetProcessors returns all the clause processors registered to a QueryDSL
 func (qd *QueryDSL) GetProcessors() map[clause.ClauseType]clause.ClauseProcessor {
 	return qd.clauseProcessors
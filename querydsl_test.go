@@ -2,12 +2,15 @@ package querydsl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/olivere/elastic/v7"
 
 	"github.com/cyverse-de/querydsl/v2/clause"
+	"github.com/cyverse-de/querydsl/v2/clauseutils"
 )
 
 func TestIsQuery_IsClause(t *testing.T) {
@@ -225,3 +228,465 @@ func TestTranslateQuery(t *testing.T) {
 		testSection(t, section, "must")
 	})
 }
+
+func TestTranslateScoped(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+	qd.AddClauseType("fileonly", func(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+		return elastic.NewTermQuery("user", "arbitrary"), nil
+	}, clause.ClauseDocumentation{Kinds: []clause.DocumentKind{clause.File}})
+	qd.SetKindIndex(clause.File, "data")
+	qd.SetKindIndex(clause.Folder, "data")
+
+	fileOnly := Clause{Type: "fileonly"}
+	query := Query{All: []*GenericClause{{Clause: &testClause}}, Any: []*GenericClause{{Query: &Query{All: []*GenericClause{{Clause: &fileOnly}}}}}}
+
+	cases := []struct {
+		kinds            []clause.DocumentKind
+		expectedWarnings int
+		shouldErr        bool
+	}{
+		{kinds: []clause.DocumentKind{clause.File}},
+		{kinds: []clause.DocumentKind{clause.Folder}, expectedWarnings: 1},
+		{kinds: []clause.DocumentKind{clause.File, clause.Folder}, expectedWarnings: 1},
+		{shouldErr: true}, // no kinds
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.kinds), func(t *testing.T) {
+			scoped, err := qd.TranslateScoped(context.Background(), &query, c.kinds...)
+			if c.shouldErr && err == nil {
+				t.Errorf("TranslateScoped should have failed, instead returned nil error and query %+v", scoped)
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("TranslateScoped failed with error: %q", err)
+			} else if !c.shouldErr {
+				if len(scoped.Warnings) != c.expectedWarnings {
+					t.Errorf("Got warnings %v, expected %d of them", scoped.Warnings, c.expectedWarnings)
+				}
+				if len(scoped.Indices) != 1 || scoped.Indices[0] != "data" {
+					t.Errorf("Got indices %v rather than [data]", scoped.Indices)
+				}
+
+				querySource, err := scoped.Query.Source()
+				if err != nil {
+					t.Errorf("Source get failed with error: %q", err)
+				}
+				boolQuery, ok := querySource.(map[string]interface{})["bool"]
+				if !ok {
+					t.Fatal("did not contain 'bool'")
+				}
+				filter, ok := boolQuery.(map[string]interface{})["filter"]
+				if !ok {
+					t.Fatal("bool did not contain 'filter'")
+				}
+				if len(c.kinds) == 1 {
+					_, ok = filter.(map[string]interface{})["term"]
+				} else {
+					_, ok = filter.(map[string]interface{})["terms"]
+				}
+				if !ok {
+					t.Errorf("filter %+v was not the expected kind filter", filter)
+				}
+			}
+		})
+	}
+}
+
+func TestSetUserResolver(t *testing.T) {
+	qd := New()
+	qd.AddClauseType("whoami", func(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+		resolved, err := clauseutils.ResolveUser(ctx, "mian")
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewTermsQuery("user", clauseutils.StringsToInterfaces(resolved.Terms)...), nil
+	}, clause.ClauseDocumentation{})
+	qd.SetUserResolver(clauseutils.ZoneUserResolver{Zones: []string{"iplant"}})
+
+	whoami := Clause{Type: "whoami"}
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"from-querydsl", context.Background(), "mian#iplant"},
+		{"from-context", clauseutils.WithUserResolver(context.Background(), clauseutils.ZoneUserResolver{Zones: []string{"other"}}), "mian#other"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			translated, err := whoami.Translate(c.ctx, qd)
+			if err != nil {
+				t.Fatalf("Translate failed with error: %q", err)
+			}
+			querySource, err := translated.Source()
+			if err != nil {
+				t.Errorf("Source get failed with error: %q", err)
+			}
+			termsQuery := querySource.(map[string]interface{})["terms"].(map[string]interface{})
+			users := termsQuery["user"].([]interface{})
+			if len(users) != 1 || users[0].(string) != c.expected {
+				t.Errorf("terms user query was %v rather than [%s]", users, c.expected)
+			}
+		})
+	}
+}
+
+func TestAddMetadataNamespace(t *testing.T) {
+	qd := New()
+	if names := qd.GetMetadataNamespaces().Names(); !reflect.DeepEqual(names, []string{"irods", "cyverse"}) {
+		t.Errorf("Got default namespaces %v, expected irods and cyverse", names)
+	}
+
+	qd.AddMetadataNamespace(clauseutils.MetadataNamespace{Name: "ontology", Documentation: "Ontology annotations"})
+	qd.AddMetadataNamespace(clauseutils.MetadataNamespace{Name: "irods", Path: "avus"})
+	namespaces := qd.GetMetadataNamespaces()
+	if names := namespaces.Names(); !reflect.DeepEqual(names, []string{"irods", "cyverse", "ontology"}) {
+		t.Errorf("Got namespaces %v, expected irods, cyverse, and ontology", names)
+	}
+	if ns, _ := namespaces.Lookup("irods"); ns.NestedPath() != "avus" {
+		t.Errorf("The irods namespace was not replaced, got %+v", ns)
+	}
+	if len(clauseutils.DefaultMetadataNamespaces) != 2 {
+		t.Errorf("Adding namespaces modified the defaults: %+v", clauseutils.DefaultMetadataNamespaces)
+	}
+
+	qd.AddClauseType("namespaces", func(ctx context.Context, args map[string]interface{}) (elastic.Query, error) {
+		names := clauseutils.MetadataNamespacesOrDefault(ctx).Names()
+		return elastic.NewTermsQuery("namespace", clauseutils.StringsToInterfaces(names)...), nil
+	}, clause.ClauseDocumentation{})
+	translated, err := (&Clause{Type: "namespaces"}).Translate(context.Background(), qd)
+	if err != nil {
+		t.Fatalf("Translate failed with error: %q", err)
+	}
+	source, _ := translated.Source()
+	terms := source.(map[string]interface{})["terms"].(map[string]interface{})["namespace"].([]interface{})
+	if len(terms) != 3 {
+		t.Errorf("Clauses saw namespaces %v rather than the configured ones", terms)
+	}
+}
+
+func TestTranslateFor(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+	qd.SetUserResolver(clauseutils.ZoneUserResolver{Zones: []string{"iplant"}})
+
+	principal := &Principal{Username: "mian", Groups: []string{"lab#iplant"}}
+	expectedFilter := elastic.NewNestedQuery("userPermissions", elastic.NewBoolQuery().MinimumNumberShouldMatch(1).Should(
+		elastic.NewTermsQuery("userPermissions.user", "mian#iplant", "lab#iplant"),
+	))
+	expectedFilterSource, err := expectedFilter.Source()
+	if err != nil {
+		t.Fatalf("Source get on expected filter failed with error: %q", err)
+	}
+
+	leaf := func() *GenericClause { return &GenericClause{Clause: &testClause} }
+	cases := []struct {
+		name  string
+		query Query
+	}{
+		{"empty", Query{}},
+		{"all", Query{All: []*GenericClause{leaf()}}},
+		{"any", Query{Any: []*GenericClause{leaf(), leaf()}}},
+		{"none", Query{None: []*GenericClause{leaf()}}},
+		{"none-of-none", Query{None: []*GenericClause{{Query: &Query{None: []*GenericClause{leaf()}}}}}},
+		{"any-of-none", Query{Any: []*GenericClause{{Query: &Query{None: []*GenericClause{leaf()}}}, leaf()}}},
+		{"deep", Query{None: []*GenericClause{{Query: &Query{Any: []*GenericClause{{Query: &Query{None: []*GenericClause{{Query: &Query{All: []*GenericClause{leaf()}}}}}}}}}}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			translated, err := qd.TranslateFor(context.Background(), principal, &c.query)
+			if err != nil {
+				t.Fatalf("TranslateFor failed with error: %q", err)
+			}
+			querySource, err := translated.Source()
+			if err != nil {
+				t.Fatalf("Source get failed with error: %q", err)
+			}
+
+			boolQuery, ok := querySource.(map[string]interface{})["bool"].(map[string]interface{})
+			if !ok || len(querySource.(map[string]interface{})) != 1 {
+				t.Fatalf("top-level query %+v was not solely a bool query", querySource)
+			}
+
+			// The user's query may only appear in 'must', so it can only narrow the filtered results
+			for key := range boolQuery {
+				if key != "must" && key != "filter" {
+					t.Errorf("top-level bool query had unexpected key %q", key)
+				}
+			}
+
+			filter, ok := boolQuery["filter"]
+			if !ok {
+				t.Fatal("top-level bool query did not contain 'filter'")
+			}
+			if !reflect.DeepEqual(filter, expectedFilterSource) {
+				t.Errorf("filter %+v was not the expected security filter %+v", filter, expectedFilterSource)
+			}
+
+			expectedInner, err := c.query.Translate(context.Background(), qd)
+			if err != nil {
+				t.Fatalf("Translate failed with error: %q", err)
+			}
+			expectedInnerSource, err := expectedInner.Source()
+			if err != nil {
+				t.Fatalf("Source get failed with error: %q", err)
+			}
+			if !reflect.DeepEqual(boolQuery["must"], expectedInnerSource) {
+				t.Errorf("'must' %+v was not the translated query %+v", boolQuery["must"], expectedInnerSource)
+			}
+		})
+	}
+
+	t.Run("no-principal", func(t *testing.T) {
+		if _, err := qd.TranslateFor(context.Background(), &Principal{}, &Query{}); err == nil {
+			t.Error("TranslateFor did not return an error without a principal username")
+		}
+		if _, err := qd.TranslateFor(context.Background(), nil, &Query{}); err == nil {
+			t.Error("TranslateFor did not return an error with a nil principal")
+		}
+	})
+}
+
+func TestTranslateForInexactPrincipals(t *testing.T) {
+	qd, _ := addTestingClauseType()
+
+	cases := []struct {
+		name      string
+		resolver  clauseutils.UserResolver
+		principal *Principal
+		expected  []interface{}
+	}{
+		{"qualified", nil, &Principal{Username: "mian#iplant"}, []interface{}{"mian#iplant"}},
+		{"unqualified", nil, &Principal{Username: "mian"}, nil},
+		{"unqualified-wildcard-resolver", clauseutils.WildcardUserResolver{}, &Principal{Username: "mian"}, nil},
+		{"star", nil, &Principal{Username: "*"}, nil},
+		{"star-zone", nil, &Principal{Username: "*#iplant"}, nil},
+		{"prefix", clauseutils.ZoneUserResolver{Zones: []string{"iplant"}}, &Principal{Username: "a*"}, nil},
+		{"question-mark", nil, &Principal{Username: "mia?#iplant"}, nil},
+		{"backslash", nil, &Principal{Username: `mian\#iplant`}, nil},
+		{"star-group", clauseutils.ZoneUserResolver{Zones: []string{"iplant"}}, &Principal{Username: "mian", Groups: []string{"*"}}, nil},
+		{"unqualified-group", nil, &Principal{Username: "mian#iplant", Groups: []string{"lab"}}, nil},
+		{"wildcard-from-resolver", clauseutils.UserLookupFunc(func(_ context.Context, username string) ([]string, error) {
+			return []string{username + "#*"}, nil
+		}), &Principal{Username: "mian"}, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.resolver != nil {
+				ctx = clauseutils.WithUserResolver(ctx, c.resolver)
+			}
+			translated, err := qd.TranslateFor(ctx, c.principal, &Query{})
+			if c.expected == nil {
+				if err == nil {
+					source, _ := translated.Source()
+					t.Errorf("TranslateFor should have rejected principal %+v, instead returned %+v", c.principal, source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TranslateFor failed with error: %q", err)
+			}
+			source, _ := translated.Source()
+			filter := source.(map[string]interface{})["bool"].(map[string]interface{})["filter"].(map[string]interface{})
+			inner := filter["nested"].(map[string]interface{})["query"].(map[string]interface{})["bool"].(map[string]interface{})
+			terms := inner["should"].(map[string]interface{})["terms"].(map[string]interface{})["userPermissions.user"]
+			if !reflect.DeepEqual(terms, c.expected) {
+				t.Errorf("security filter matched users %v rather than %v", terms, c.expected)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+	qd.AddClauseType("perms", func(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+		return elastic.NewTermQuery("user", "arbitrary"), nil
+	}, clause.ClauseDocumentation{})
+	qd.AddClauseType("raw", func(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+		return elastic.NewTermQuery("user", "arbitrary"), nil
+	}, clause.ClauseDocumentation{})
+	qd.SetClausePolicy("perms", clause.ClausePolicy{SelfOnlyArgs: []string{"users"}, ExemptRoles: []string{"admin"}})
+	qd.SetClausePolicy("raw", clause.ClausePolicy{AllowedRoles: []string{"admin"}})
+	qd.SetAuthorizer(func(_ context.Context, clauseType clause.ClauseType, args map[string]interface{}) error {
+		if args["forbidden"] == true {
+			return fmt.Errorf("clause of type %s is forbidden", clauseType)
+		}
+		return nil
+	})
+
+	user := &Principal{Username: "mian#iplant"}
+	unqualified := &Principal{Username: "mian"}
+	admin := &Principal{Username: "ipctest#iplant", Roles: []string{"admin"}}
+	iplant := clauseutils.ZoneUserResolver{Zones: []string{"iplant"}}
+	allZones := clauseutils.ZoneUserResolver{Zones: []string{"iplant", "other"}}
+
+	cases := []struct {
+		name       string
+		principal  *Principal
+		resolver   clauseutils.UserResolver
+		clause     Clause
+		authorized bool
+	}{
+		{"unrestricted", user, nil, testClause, true},
+		{"authorizer", user, nil, Clause{Type: "foo", Args: map[string]interface{}{"forbidden": true}}, false},
+		{"self", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []interface{}{"mian#iplant"}}}, true},
+		{"self-string-slice", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian#iplant"}}}, true},
+		{"self-unqualified", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian"}}}, false},
+		{"self-unqualified-resolved", user, iplant, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian"}}}, true},
+		{"self-unqualified-other-zones", user, allZones, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian"}}}, false},
+		{"self-wildcard", user, clauseutils.WildcardUserResolver{}, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian"}}}, false},
+		{"self-pattern", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian#*"}}}, false},
+		{"other-zone", unqualified, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian#otherzone"}}}, false},
+		{"other-zone-resolved", unqualified, iplant, Clause{Type: "perms", Args: map[string]interface{}{"users": []string{"mian#otherzone"}}}, false},
+		{"other", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []interface{}{"mian#iplant", "ipctest#iplant"}}}, false},
+		{"other-case", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"Users": []interface{}{"victim#iplant"}}}, false},
+		{"other-case-alongside-self", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []interface{}{"mian#iplant"}, "USERS": "victim#iplant"}}, false},
+		{"self-case", user, nil, Clause{Type: "perms", Args: map[string]interface{}{"Users": []interface{}{"mian#iplant"}}}, true},
+		{"other-exempt", admin, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []interface{}{"mian"}}}, true},
+		{"other-no-principal", nil, nil, Clause{Type: "perms", Args: map[string]interface{}{"users": []interface{}{"mian#iplant"}}}, false},
+		{"role", admin, nil, Clause{Type: "raw"}, true},
+		{"role-missing", user, nil, Clause{Type: "raw"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.resolver != nil {
+				ctx = clauseutils.WithUserResolver(ctx, c.resolver)
+			}
+			if c.principal != nil {
+				ctx = WithPrincipal(ctx, c.principal)
+			}
+			query := Query{Any: []*GenericClause{{Query: &Query{None: []*GenericClause{{Clause: &c.clause}}}}}}
+
+			validateErr := query.Validate(ctx, qd)
+			_, translateErr := query.Translate(ctx, qd)
+			if c.authorized && (validateErr != nil || translateErr != nil) {
+				t.Errorf("Clause should have been authorized, but got errors %q and %q", validateErr, translateErr)
+			} else if !c.authorized && (validateErr == nil || translateErr == nil) {
+				t.Errorf("Clause should not have been authorized, but got errors %v and %v", validateErr, translateErr)
+			}
+		})
+	}
+
+	t.Run("policy-error", func(t *testing.T) {
+		err := qd.Authorize(WithPrincipal(context.Background(), user), "raw", nil)
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Authorize returned %v rather than an ErrUnauthorized", err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+
+	cases := []struct {
+		name      string
+		query     Query
+		shouldErr bool
+	}{
+		{"valid", Query{All: []*GenericClause{{Clause: &testClause}}}, false},
+		{"unknown-type", Query{Any: []*GenericClause{{Query: &Query{All: []*GenericClause{{Clause: &Clause{Type: "type-that-doesnt-exist"}}}}}}}, true},
+		{"malformed", Query{All: []*GenericClause{{}}}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.query.Validate(context.Background(), qd)
+			if c.shouldErr && err == nil {
+				t.Error("Validate should have failed, but did not")
+			} else if !c.shouldErr && err != nil {
+				t.Errorf("Validate failed with error: %q", err)
+			}
+		})
+	}
+}
+
+func addCostedClauseType(qd *QueryDSL) {
+	qd.AddClauseType("costly", func(_ context.Context, args map[string]interface{}) (elastic.Query, error) {
+		return elastic.NewTermQuery("user", "arbitrary"), nil
+	}, clause.ClauseDocumentation{})
+	qd.SetClauseCoster("costly", func(_ context.Context, args map[string]interface{}) (clause.ClauseCost, error) {
+		return clause.ClauseCost{Cost: 10, Terms: 3, LeadingWildcards: 1, TagLookups: 2}, nil
+	})
+}
+
+func TestCost(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+	addCostedClauseType(qd)
+	costly := Clause{Type: "costly"}
+	withTerms := Clause{Type: "foo", Args: map[string]interface{}{"users": []string{"a", "b"}}}
+
+	query := Query{
+		All:  []*GenericClause{{Clause: &testClause}, {Clause: &withTerms}},
+		None: []*GenericClause{{Query: &Query{Any: []*GenericClause{{Clause: &costly}}}}},
+	}
+
+	cost, err := qd.Cost(context.Background(), &query)
+	if err != nil {
+		t.Fatalf("Cost failed with error: %q", err)
+	}
+
+	expected := QueryCost{Cost: 13, Clauses: 3, Depth: 2, Terms: 6, MaxClauseTerms: 3, LeadingWildcards: 1, TagLookups: 2}
+	if *cost != expected {
+		t.Errorf("Cost returned %+v rather than %+v", *cost, expected)
+	}
+
+	unknown := Query{All: []*GenericClause{{Clause: &Clause{Type: "type-that-doesnt-exist"}}}}
+	if _, err := qd.Cost(context.Background(), &unknown); err == nil {
+		t.Error("Cost did not return an error using nonexistent clause type, which it should")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	qd, testClause := addTestingClauseType()
+	addCostedClauseType(qd)
+	costly := Clause{Type: "costly"}
+
+	leaf := func() *GenericClause { return &GenericClause{Clause: &testClause} }
+	nested := Query{Any: []*GenericClause{{Query: &Query{None: []*GenericClause{{Query: &Query{All: []*GenericClause{leaf()}}}}}}}}
+	wide := Query{Any: []*GenericClause{leaf(), leaf(), leaf(), leaf()}}
+	expensive := Query{All: []*GenericClause{{Clause: &costly}}}
+
+	cases := []struct {
+		name      string
+		limits    Limits
+		query     Query
+		shouldErr bool
+	}{
+		{"unlimited", Limits{}, nested, false},
+		{"depth-ok", Limits{MaxDepth: 3}, nested, false},
+		{"depth", Limits{MaxDepth: 2}, nested, true},
+		{"clauses-ok", Limits{MaxClauses: 4}, wide, false},
+		{"clauses", Limits{MaxClauses: 3}, wide, true},
+		{"terms", Limits{MaxTermsPerClause: 2}, expensive, true},
+		{"leading-wildcards", Limits{MaxLeadingWildcards: 1}, Query{All: []*GenericClause{{Clause: &costly}, {Clause: &costly}}}, true},
+		{"leading-wildcards-forbidden", Limits{ForbidLeadingWildcards: true}, expensive, true},
+		{"leading-wildcards-forbidden-ok", Limits{ForbidLeadingWildcards: true}, wide, false},
+		{"tag-lookups", Limits{MaxTagLookups: 1}, expensive, true},
+		{"cost", Limits{MaxCost: 5}, expensive, true},
+		{"cost-ok", Limits{MaxCost: 5}, wide, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			qd.SetLimits(c.limits)
+
+			_, translateErr := c.query.Translate(context.Background(), qd)
+			validateErr := c.query.Validate(context.Background(), qd)
+			if c.shouldErr {
+				if !errors.Is(translateErr, ErrLimitExceeded) {
+					t.Errorf("Translate returned %v rather than an ErrLimitExceeded", translateErr)
+				}
+				if !errors.Is(validateErr, ErrLimitExceeded) {
+					t.Errorf("Validate returned %v rather than an ErrLimitExceeded", validateErr)
+				}
+			} else if translateErr != nil || validateErr != nil {
+				t.Errorf("Query should have been within limits, but got errors %q and %q", translateErr, validateErr)
+			}
+		})
+	}
+}